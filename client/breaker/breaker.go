@@ -0,0 +1,262 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"lib/config"
+	errs "lib/error"
+	"lib/log"
+	"sync"
+	"time"
+)
+
+// ErrOpen 熔断器打开或半开状态探测请求已满时拒绝调用
+var ErrOpen = errs.NewWithCode(errs.CodeServiceUnavailable, "breaker: circuit open")
+
+type State int32
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// Breaker 熔断器，可以并发使用
+//   - closed：正常调用，滑动窗口内请求数达到 min_requests 且错误率达到 error_percent 时打开
+//   - open：直接返回 ErrOpen，经过 open_ms 后进入半开状态
+//   - half-open：最多放行 half_open_requests 个探测请求，全部成功后关闭，任意一个失败则重新打开
+//
+// 状态变化时记录日志并通过 Metrics 上报
+type Breaker struct {
+	name      string
+	opts      *Options
+	isFailure func(err error) bool
+	metrics   Metrics
+	logger    *log.Logger
+	now       func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	window     *window
+	openUntil  time.Time
+	probes     int
+	successes  int
+}
+
+// NewBreaker 从配置文件创建熔断器，name 用于日志和指标，配置错误时 panic
+func NewBreaker(name, file string) *Breaker {
+	return NewBreakerWithOptions(name, newOptions(file))
+}
+
+// NewDefaultBreaker 使用默认配置文件 breaker.ini
+func NewDefaultBreaker(name string) *Breaker {
+	return NewBreaker(name, config.DefaultBreakerConfigFile)
+}
+
+func NewBreakerWithOptions(name string, opts *Options) *Breaker {
+	return &Breaker{
+		name:      name,
+		opts:      opts,
+		isFailure: IsFailure,
+		metrics:   nopMetrics{},
+		logger:    log.NewLogger(),
+		now:       time.Now,
+		window:    newWindow(opts.GetWindow(), opts.GetWindowBuckets()),
+	}
+}
+
+// IsFailure 默认的失败判断：主动取消和 4xx 业务错误不计为失败
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		if code := int(e.Code()); code >= 400 && code < 500 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) Log() *log.Logger {
+	return b.logger
+}
+
+// SetFailureFunc 替换失败判断，需要在使用之前调用
+func (b *Breaker) SetFailureFunc(fn func(err error) bool) {
+	b.isFailure = fn
+}
+
+// SetMetrics 设置指标上报，需要在使用之前调用
+func (b *Breaker) SetMetrics(m Metrics) {
+	b.metrics = m
+}
+
+// IsFailure 按 SetFailureFunc 设置的规则判断 err 是否计为失败
+func (b *Breaker) IsFailure(err error) bool {
+	return b.isFailure(err)
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	change := b.advance(b.now())
+	state := b.state
+	b.mu.Unlock()
+
+	b.emit(change)
+	return state
+}
+
+// Do 通过熔断器执行 fn，fn 返回的错误按 IsFailure 计入统计，panic 计为失败
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			done(false)
+			panic(r)
+		}
+	}()
+
+	err = fn()
+	done(!b.isFailure(err))
+	return err
+}
+
+// Allow 检查是否允许调用，允许时返回的 done 必须在调用结束后执行且只执行一次
+// 用于无法包装成 Do 的场景，如 HTTP 中间件根据状态码判断结果
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	change := b.advance(b.now())
+
+	rejected := false
+	switch b.state {
+	case StateOpen:
+		rejected = true
+	case StateHalfOpen:
+		if b.probes >= b.opts.GetHalfOpenRequests() {
+			rejected = true
+		} else {
+			b.probes++
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+
+	b.emit(change)
+	if rejected {
+		b.metrics.Result(b.name, ResultRejected)
+		return nil, ErrOpen
+	}
+
+	return func(success bool) {
+		b.done(generation, success)
+	}, nil
+}
+
+func (b *Breaker) done(generation uint64, success bool) {
+	if success {
+		b.metrics.Result(b.name, ResultSuccess)
+	} else {
+		b.metrics.Result(b.name, ResultFailure)
+	}
+
+	b.mu.Lock()
+	// 调用开始后状态已经变化，结果不再计入
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	now := b.now()
+	var change *stateChange
+	switch b.state {
+	case StateClosed:
+		b.window.record(now, !success)
+		if !success && b.shouldTrip(now) {
+			change = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.probes--
+		if !success {
+			change = b.setState(StateOpen, now)
+		} else if b.successes++; b.successes >= b.opts.GetHalfOpenRequests() {
+			change = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+
+	b.emit(change)
+}
+
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	requests, failures := b.window.counts(now)
+	if requests < b.opts.GetMinRequests() {
+		return false
+	}
+	return failures*100 >= requests*b.opts.GetErrorPercent()
+}
+
+// 打开状态超时后进入半开状态，需要持有锁
+func (b *Breaker) advance(now time.Time) *stateChange {
+	if b.state == StateOpen && !now.Before(b.openUntil) {
+		return b.setState(StateHalfOpen, now)
+	}
+	return nil
+}
+
+type stateChange struct {
+	from, to State
+}
+
+// 需要持有锁，返回的变化在释放锁之后通过 emit 输出
+func (b *Breaker) setState(state State, now time.Time) *stateChange {
+	change := &stateChange{from: b.state, to: state}
+
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+	b.window.reset()
+	if state == StateOpen {
+		b.openUntil = now.Add(b.opts.GetOpenTimeout())
+	}
+
+	return change
+}
+
+func (b *Breaker) emit(change *stateChange) {
+	if change == nil {
+		return
+	}
+
+	if change.to == StateOpen {
+		b.logger.Warn("breaker:", b.name, "state changed from", change.from, "to", change.to)
+	} else {
+		b.logger.Info("breaker:", b.name, "state changed from", change.from, "to", change.to)
+	}
+	b.metrics.StateChange(b.name, change.from, change.to)
+}
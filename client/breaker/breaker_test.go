@@ -0,0 +1,218 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"lib/config/proto"
+	errs "lib/error"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+type testMetrics struct {
+	mu      sync.Mutex
+	changes []string
+	results map[string]int
+}
+
+func (m *testMetrics) StateChange(name string, from, to State) {
+	m.mu.Lock()
+	m.changes = append(m.changes, from.String()+"->"+to.String())
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) Result(name string, result string) {
+	m.mu.Lock()
+	m.results[result]++
+	m.mu.Unlock()
+}
+
+func newTestBreaker(now *time.Time) (*Breaker, *testMetrics) {
+	b := NewBreakerWithOptions("test", &Options{BreakerConfig: proto.BreakerConfig{
+		WindowMs:         1000,
+		WindowBuckets:    10,
+		MinRequests:      4,
+		ErrorPercent:     50,
+		OpenMs:           500,
+		HalfOpenRequests: 2,
+	}})
+	b.now = func() time.Time { return *now }
+
+	m := &testMetrics{results: make(map[string]int)}
+	b.SetMetrics(m)
+	return b, m
+}
+
+func call(b *Breaker, err error) error {
+	return b.Do(func() error { return err })
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, m := newTestBreaker(&now)
+
+	// 未达到 min_requests 时不熔断
+	for i := 0; i < 3; i++ {
+		if err := call(b, errDown); err != errDown {
+			t.Fatalf("expected downstream error, got %v", err)
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed below min requests, got %s", b.State())
+	}
+
+	// 第 4 个请求失败，错误率 100%
+	_ = call(b, errDown)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if err := call(b, nil); err != ErrOpen {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	// 超时后半开，最多放行 2 个探测请求
+	now = now.Add(500 * time.Millisecond)
+	done1, err := b.Allow()
+	if err != nil || b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open probe, got %v %s", err, b.State())
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expected second probe, got %v", err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatalf("expected probes to be limited, got %v", err)
+	}
+	done1(true)
+	done2(false)
+	if b.State() != StateOpen {
+		t.Fatalf("expected failed probe to reopen, got %s", b.State())
+	}
+
+	// 探测全部成功后关闭
+	now = now.Add(500 * time.Millisecond)
+	_ = call(b, nil)
+	_ = call(b, nil)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after successful probes, got %s", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(m.changes) != len(want) {
+		t.Fatalf("expected changes %v, got %v", want, m.changes)
+	}
+	for i := range want {
+		if m.changes[i] != want[i] {
+			t.Fatalf("expected changes %v, got %v", want, m.changes)
+		}
+	}
+	if m.results[ResultRejected] != 2 || m.results[ResultFailure] != 5 || m.results[ResultSuccess] != 3 {
+		t.Errorf("unexpected results %v", m.results)
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, _ := newTestBreaker(&now)
+
+	// 旧的失败滑出窗口后不再计入
+	_ = call(b, errDown)
+	_ = call(b, errDown)
+	_ = call(b, errDown)
+	now = now.Add(1100 * time.Millisecond)
+	_ = call(b, errDown)
+	if b.State() != StateClosed {
+		t.Fatalf("expected expired failures to be ignored, got %s", b.State())
+	}
+
+	// 错误率低于 50% 不熔断
+	for i := 0; i < 4; i++ {
+		_ = call(b, nil)
+	}
+	_ = call(b, errDown)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed at 40%% error rate, got %s", b.State())
+	}
+
+	// 4xx 业务错误和主动取消不计为失败
+	for i := 0; i < 10; i++ {
+		_ = call(b, errs.NewWithCode(errs.CodeNotFound, "not found"))
+		_ = call(b, context.Canceled)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected business errors to be ignored, got %s", b.State())
+	}
+
+	// 状态变化之前开始的调用不计入新状态
+	now = now.Add(1100 * time.Millisecond)
+	done, _ := b.Allow()
+	for i := 0; i < 4; i++ {
+		_ = call(b, errDown)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	now = now.Add(500 * time.Millisecond)
+	done(false)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected stale result to be ignored, got %s", b.State())
+	}
+}
+
+func TestBreakerPanic(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, m := newTestBreaker(&now)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic to propagate")
+		}
+		if m.results[ResultFailure] != 1 {
+			t.Errorf("expected panic to count as failure, got %v", m.results)
+		}
+	}()
+	_ = b.Do(func() error { panic("boom") })
+}
+
+func TestBulkhead(t *testing.T) {
+	b := NewBulkheadWithOptions("test", &Options{BreakerConfig: proto.BreakerConfig{
+		MaxConcurrent: 2,
+		MaxWaitMs:     50,
+	}})
+
+	release1, _ := b.Acquire(context.Background())
+	release2, _ := b.Acquire(context.Background())
+	if b.Inflight() != 2 {
+		t.Fatalf("expected 2 inflight, got %d", b.Inflight())
+	}
+
+	start := time.Now()
+	if _, err := b.Acquire(context.Background()); err != ErrBulkheadFull {
+		t.Fatalf("expected ErrBulkheadFull, got %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected to wait max_wait_ms before rejecting")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Acquire(ctx); err != context.Canceled {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+
+	// 等待期间有名额释放
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release1()
+	}()
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatalf("expected to acquire released slot, got %v", err)
+	}
+	release2()
+	if b.Inflight() != 0 {
+		t.Errorf("expected 0 inflight, got %d", b.Inflight())
+	}
+}
@@ -0,0 +1,98 @@
+package breaker
+
+import (
+	"context"
+	"lib/config"
+	errs "lib/error"
+	"time"
+)
+
+// ErrBulkheadFull 并发数已满且等待超时
+var ErrBulkheadFull = errs.NewWithCode(errs.CodeServiceUnavailable, "bulkhead: max concurrency reached")
+
+// Bulkhead 隔离舱，限制对下游的并发调用数，避免下游变慢时调用方堆积大量 goroutine
+// 可以并发使用
+type Bulkhead struct {
+	name    string
+	opts    *Options
+	sem     chan struct{}
+	metrics Metrics
+}
+
+// NewBulkhead 从配置文件创建隔离舱，使用 max_concurrent 和 max_wait_ms，配置错误时 panic
+func NewBulkhead(name, file string) *Bulkhead {
+	return NewBulkheadWithOptions(name, newOptions(file))
+}
+
+// NewDefaultBulkhead 使用默认配置文件 breaker.ini
+func NewDefaultBulkhead(name string) *Bulkhead {
+	return NewBulkhead(name, config.DefaultBreakerConfigFile)
+}
+
+func NewBulkheadWithOptions(name string, opts *Options) *Bulkhead {
+	return &Bulkhead{
+		name:    name,
+		opts:    opts,
+		sem:     make(chan struct{}, opts.GetMaxConcurrent()),
+		metrics: nopMetrics{},
+	}
+}
+
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// SetMetrics 设置指标上报，需要在使用之前调用
+func (b *Bulkhead) SetMetrics(m Metrics) {
+	b.metrics = m
+}
+
+// Inflight 当前正在执行的调用数
+func (b *Bulkhead) Inflight() int {
+	return len(b.sem)
+}
+
+// Acquire 获取一个并发名额，最多等待 max_wait_ms 或直到 ctx 结束
+// 成功时返回的 release 必须在调用结束后执行
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case b.sem <- struct{}{}:
+		return b.release, nil
+	default:
+	}
+
+	if wait := b.opts.GetMaxWait(); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case b.sem <- struct{}{}:
+			return b.release, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	b.metrics.Result(b.name, ResultRejected)
+	return nil, ErrBulkheadFull
+}
+
+func (b *Bulkhead) release() {
+	<-b.sem
+}
+
+// Do 获取并发名额后执行 fn
+func (b *Bulkhead) Do(fn func() error) error {
+	return b.DoContext(context.Background(), fn)
+}
+
+func (b *Bulkhead) DoContext(ctx context.Context, fn func() error) error {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn()
+}
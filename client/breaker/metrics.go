@@ -0,0 +1,22 @@
+package breaker
+
+const (
+	ResultSuccess  = "success"
+	ResultFailure  = "failure"
+	ResultRejected = "rejected"
+)
+
+// Metrics 指标上报，可以对接 prometheus 等监控系统
+// 方法在调用路径上同步执行，需要足够轻量
+type Metrics interface {
+	// StateChange 熔断器状态变化
+	StateChange(name string, from, to State)
+	// Result 调用结果，为 ResultSuccess、ResultFailure 或 ResultRejected
+	Result(name string, result string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) StateChange(name string, from, to State) {}
+
+func (nopMetrics) Result(name string, result string) {}
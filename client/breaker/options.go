@@ -0,0 +1,90 @@
+package breaker
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"time"
+)
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+type Options struct {
+	proto.BreakerConfig
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.BreakerConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if opts.ErrorPercent < 0 || opts.ErrorPercent > 100 {
+		panic("breaker: error_percent must be between 0 and 100")
+	}
+}
+
+// 统计错误率的滑动窗口长度
+func (opt *Options) GetWindow() time.Duration {
+	if opt.WindowMs <= 0 {
+		return time.Duration(10) * time.Second
+	}
+	return time.Duration(opt.WindowMs) * time.Millisecond
+}
+
+// 滑动窗口的分桶数，越多越平滑
+func (opt *Options) GetWindowBuckets() int {
+	if opt.WindowBuckets <= 0 {
+		return 10
+	}
+	return opt.WindowBuckets
+}
+
+// 窗口内请求数达到该值后才计算错误率
+func (opt *Options) GetMinRequests() int {
+	if opt.MinRequests <= 0 {
+		return 20
+	}
+	return opt.MinRequests
+}
+
+// 错误率达到该百分比时熔断
+func (opt *Options) GetErrorPercent() int {
+	if opt.ErrorPercent <= 0 {
+		return 50
+	}
+	return opt.ErrorPercent
+}
+
+// 熔断后经过该时间进入半开状态
+func (opt *Options) GetOpenTimeout() time.Duration {
+	if opt.OpenMs <= 0 {
+		return time.Duration(5) * time.Second
+	}
+	return time.Duration(opt.OpenMs) * time.Millisecond
+}
+
+// 半开状态允许的探测请求数，全部成功后恢复
+func (opt *Options) GetHalfOpenRequests() int {
+	if opt.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return opt.HalfOpenRequests
+}
+
+// 隔离舱的最大并发数
+func (opt *Options) GetMaxConcurrent() int {
+	if opt.MaxConcurrent <= 0 {
+		return 100
+	}
+	return opt.MaxConcurrent
+}
+
+// 并发数已满时的最长等待时间，0 表示不等待直接拒绝
+func (opt *Options) GetMaxWait() time.Duration {
+	return time.Duration(opt.MaxWaitMs) * time.Millisecond
+}
@@ -0,0 +1,58 @@
+package breaker
+
+import "time"
+
+type bucket struct {
+	epoch    int64
+	requests int
+	failures int
+}
+
+// 按时间分桶的滑动窗口，过期的桶在下次使用时清零
+type window struct {
+	buckets []bucket
+	size    time.Duration
+}
+
+func newWindow(length time.Duration, n int) *window {
+	size := length / time.Duration(n)
+	if size <= 0 {
+		size = time.Millisecond
+	}
+	return &window{buckets: make([]bucket, n), size: size}
+}
+
+func (w *window) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(w.size)
+}
+
+func (w *window) record(now time.Time, failure bool) {
+	epoch := w.epoch(now)
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if b.epoch != epoch {
+		*b = bucket{epoch: epoch}
+	}
+
+	b.requests++
+	if failure {
+		b.failures++
+	}
+}
+
+// 窗口内的请求数和失败数
+func (w *window) counts(now time.Time) (requests, failures int) {
+	oldest := w.epoch(now) - int64(len(w.buckets))
+	for _, b := range w.buckets {
+		if b.epoch > oldest {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return
+}
+
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"io"
+	"lib/client/breaker"
+	"net/http"
+	"sync"
+)
+
+// BreakerMiddleware 熔断中间件，网络错误和 5xx 响应计为失败
+// 熔断时返回 breaker.ErrOpen，不会重试
+func BreakerMiddleware(b *breaker.Breaker) Middleware {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		done, err := b.Allow()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := next(req)
+		if err != nil {
+			done(!b.IsFailure(err))
+		} else {
+			done(resp.StatusCode < http.StatusInternalServerError)
+		}
+		return resp, err
+	}
+}
+
+// BulkheadMiddleware 并发限制中间件，名额在响应的 Body 关闭后释放
+// 并发数已满时返回 breaker.ErrBulkheadFull，不会重试
+func BulkheadMiddleware(b *breaker.Bulkhead) Middleware {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		release, err := b.Acquire(req.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := next(req)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
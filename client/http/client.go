@@ -0,0 +1,299 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"lib/client/breaker"
+	"lib/config"
+	"lib/log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const HeaderXRequestID = "X-Request-Id"
+
+// Handler 发送请求并返回响应
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware 请求中间件，可以修改请求、检查响应，不调用 next 时请求被中断
+// 重试时每次请求都会执行
+type Middleware func(req *http.Request, next Handler) (*http.Response, error)
+
+// Client HTTP 客户端，可以并发使用
+//   - 超时时间包括重试和读取响应，可以通过 WithTimeout 为单个请求设置
+//   - 网络错误和 retry_status 中的状态码按指数退避加随机抖动重试，响应带 Retry-After 时优先使用
+//   - 默认只重试幂等的请求方法，带 Idempotency-Key 头或配置 retry_all_methods 时所有方法都重试
+//   - context 中带有请求 ID 时（如 server/http 的 c.Context()）自动设置 X-Request-Id 头
+type Client struct {
+	opts         *Options
+	client       *http.Client
+	middlewares  []Middleware
+	logger       *log.Logger
+	accessLogger *log.Logger
+}
+
+// NewClient 从配置文件创建客户端，配置错误时 panic
+func NewClient(file string) *Client {
+	return NewClientWithOptions(newOptions(file))
+}
+
+// NewDefaultClient 使用默认配置文件 http_client.ini
+func NewDefaultClient() *Client {
+	return NewClient(config.DefaultHttpClientConfigFile)
+}
+
+func NewClientWithOptions(opts *Options) *Client {
+	if opts.retryStatus == nil {
+		opts.parseRetryStatus()
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.GetDialTimeout(),
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          opts.GetMaxIdleConns(),
+		MaxIdleConnsPerHost:   opts.GetMaxIdleConnsPerHost(),
+		IdleConnTimeout:       opts.GetIdleConnTimeout(),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	c := &Client{
+		opts:         opts,
+		client:       &http.Client{Transport: transport},
+		logger:       log.NewLogger(),
+		accessLogger: log.NewLogger(),
+	}
+	c.initLog()
+
+	return c
+}
+
+func (c *Client) initLog() {
+	if c.opts.AccessLog {
+		logger := log.NewLogger()
+
+		if c.opts.AccessLogDir != "" {
+			logger.SetOutputDir(c.opts.AccessLogDir)
+			logger.SetOutputByName("http_client.log")
+
+			switch c.opts.AccessLogRotate {
+			case "D", "d", "day", "daily":
+				logger.SetRotateDaily()
+			case "H", "h", "hour", "hourly":
+				logger.SetRotateHourly()
+			default:
+				logger.SetRotateHourly()
+			}
+		}
+
+		c.accessLogger = logger
+	}
+}
+
+func (c *Client) Log() *log.Logger {
+	return c.logger
+}
+
+// Use 注册中间件，按注册顺序执行，需要在发送请求之前调用
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Do 发送请求，调用方需要关闭响应的 Body
+func (c *Client) Do(req *http.Request, options ...RequestOption) (*http.Response, error) {
+	o := c.requestOptions(options)
+	for key, values := range o.header {
+		req.Header[key] = values
+	}
+	if len(o.query) > 0 {
+		query := req.URL.Query()
+		for key, values := range o.query {
+			query[key] = values
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	ctx := req.Context()
+	var cancel context.CancelFunc
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		req = req.WithContext(ctx)
+	}
+
+	if c.opts.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+	if id := log.RequestIDFromContext(ctx); id != "" && req.Header.Get(HeaderXRequestID) == "" {
+		req.Header.Set(HeaderXRequestID, id)
+	}
+
+	start := time.Now()
+	resp, attempts, err := c.send(req, o.retryMax)
+
+	if c.opts.AccessLog {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.accessLogger.Printf("%s %s %d %s attempts:%d request_id:%s err:%v",
+			req.Method, req.URL.Redacted(), status, time.Since(start), attempts, req.Header.Get(HeaderXRequestID), err)
+	}
+
+	if cancel != nil {
+		if err != nil {
+			cancel()
+		} else {
+			// 读取完响应后再释放 context
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		}
+	}
+
+	return resp, err
+}
+
+// 发送请求并按需重试，返回最后一次的响应和尝试次数
+func (c *Client) send(req *http.Request, retryMax int) (*http.Response, int, error) {
+	handler := c.chain()
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, attempt, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := handler(r)
+		if attempt >= retryMax || !c.shouldRetry(req, resp, err) {
+			return resp, attempt + 1, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if err != nil {
+			c.logger.Warn("http client:", req.Method, req.URL.Redacted(), "err:", err, ", retrying in", wait)
+		} else {
+			c.logger.Warn("http client:", req.Method, req.URL.Redacted(), "status:", resp.StatusCode, ", retrying in", wait)
+			drainBody(resp.Body)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt + 1, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) chain() Handler {
+	handler := Handler(c.client.Do)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		middleware, next := c.middlewares[i], handler
+		handler = func(req *http.Request) (*http.Response, error) {
+			return middleware(req, next)
+		}
+	}
+	return handler
+}
+
+func (c *Client) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	// 请求体无法重放
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if !c.opts.RetryAllMethods && req.Header.Get("Idempotency-Key") == "" {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		default:
+			return false
+		}
+	}
+
+	if err != nil {
+		// 熔断或并发已满时重试只会加重下游负担
+		return !errors.Is(err, breaker.ErrOpen) && !errors.Is(err, breaker.ErrBulkheadFull)
+	}
+	return c.opts.IsRetryStatus(resp.StatusCode)
+}
+
+// 指数退避，等待时间在 [wait/2, wait] 之间随机
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	min, max := c.opts.GetRetryWaitMin(), c.opts.GetRetryWaitMax()
+
+	if resp != nil {
+		if wait, ok := retryAfter(resp); ok {
+			if wait > max {
+				wait = max
+			}
+			return wait
+		}
+	}
+
+	wait := max
+	if attempt < 32 {
+		if d := min << uint(attempt); d > 0 && d < max {
+			wait = d
+		}
+	}
+
+	half := int64(wait / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Retry-After 支持秒数和 HTTP 日期两种格式
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// 读完并关闭响应，连接可以复用
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, 4096))
+	_ = body.Close()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"lib/client/breaker"
+	"lib/config/proto"
+	"lib/log"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(baseURL string, retryMax int) *Client {
+	return NewClientWithOptions(&Options{HttpClientConfig: proto.HttpClientConfig{
+		BaseUrl:        baseURL,
+		RetryMax:       retryMax,
+		RetryWaitMinMs: 1,
+		RetryWaitMaxMs: 5,
+	}})
+}
+
+// 前 failures 次请求返回 status，之后返回 200 和请求体
+func newFlakyServer(failures int32, status int, calls *int32) *httptest.Server {
+	return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		header    string
+		failures  int32
+		status    int
+		retryMax  int
+		wantCode  int
+		wantCalls int32
+	}{
+		{"retry until success", nethttp.MethodPut, "", 2, nethttp.StatusServiceUnavailable, 3, nethttp.StatusOK, 3},
+		{"retries exhausted", nethttp.MethodGet, "", 5, nethttp.StatusBadGateway, 2, nethttp.StatusBadGateway, 3},
+		{"status not retried", nethttp.MethodGet, "", 1, nethttp.StatusInternalServerError, 3, nethttp.StatusInternalServerError, 1},
+		{"post not retried", nethttp.MethodPost, "", 1, nethttp.StatusServiceUnavailable, 3, nethttp.StatusServiceUnavailable, 1},
+		{"post with idempotency key", nethttp.MethodPost, "key-1", 1, nethttp.StatusServiceUnavailable, 3, nethttp.StatusOK, 2},
+	}
+
+	for _, tt := range tests {
+		var calls int32
+		server := newFlakyServer(tt.failures, tt.status, &calls)
+		client := newTestClient(server.URL, tt.retryMax)
+
+		options := []RequestOption{}
+		if tt.header != "" {
+			options = append(options, WithHeader("Idempotency-Key", tt.header))
+		}
+		resp, err := client.Request(context.Background(), tt.method, "/items", strings.NewReader(`{"id":1}`), options...)
+		server.Close()
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if resp.StatusCode != tt.wantCode || calls != tt.wantCalls {
+			t.Errorf("%s: expected %d after %d calls, got %d after %d calls", tt.name, tt.wantCode, tt.wantCalls, resp.StatusCode, calls)
+		}
+		// 重试时请求体需要重放
+		if resp.StatusCode == nethttp.StatusOK && resp.String() != `{"id":1}` {
+			t.Errorf("%s: body not replayed, got %q", tt.name, resp.String())
+		}
+	}
+}
+
+func TestClientRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(nethttp.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	var calls int32
+	client := newTestClient(url, 2)
+	client.Use(func(req *nethttp.Request, next Handler) (*nethttp.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return next(req)
+	})
+
+	if _, err := client.Get(context.Background(), "/"); err == nil {
+		t.Fatal("expected connection error")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, 3)
+	start := time.Now()
+	_, err := client.Get(context.Background(), "/slow", WithTimeout(50*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("timeout should include retries, took %s", elapsed)
+	}
+}
+
+func TestClientJSONAndRequestID(t *testing.T) {
+	var gotID, gotType, gotOrder string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		gotID = r.Header.Get(HeaderXRequestID)
+		gotType = r.Header.Get("Content-Type")
+		gotOrder = r.Header.Get("X-Order")
+		if r.URL.Path == "/missing" {
+			nethttp.Error(w, "not found", nethttp.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"tyrion","query":"` + r.URL.RawQuery + `"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL+"/", 0)
+	client.Use(func(req *nethttp.Request, next Handler) (*nethttp.Response, error) {
+		req.Header.Set("X-Order", req.Header.Get("X-Order")+"a")
+		return next(req)
+	}, func(req *nethttp.Request, next Handler) (*nethttp.Response, error) {
+		req.Header.Set("X-Order", req.Header.Get("X-Order")+"b")
+		return next(req)
+	})
+
+	ctx := log.ContextWithRequestID(context.Background(), "req-1")
+	var out struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	}
+	if err := client.PostJSON(ctx, "/users?a=1", map[string]int{"id": 1}, &out, WithQuery(map[string][]string{"b": {"2"}})); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "tyrion" || out.Query != "a=1&b=2" {
+		t.Errorf("unexpected response %+v", out)
+	}
+	if gotID != "req-1" || gotType != MIMEApplicationJSON || gotOrder != "ab" {
+		t.Errorf("unexpected headers: request id %q, content type %q, order %q", gotID, gotType, gotOrder)
+	}
+
+	err := client.GetJSON(context.Background(), "missing", &out)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != nethttp.StatusNotFound {
+		t.Fatalf("expected StatusError 404, got %v", err)
+	}
+	if gotID != "" {
+		t.Errorf("request id should not be set without context value, got %q", gotID)
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	client := NewClientWithOptions(&Options{HttpClientConfig: proto.HttpClientConfig{
+		RetryWaitMinMs: 100,
+		RetryWaitMaxMs: 1000,
+	}})
+
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if wait := client.backoff(attempt, nil); wait < max/2 || wait > max {
+				t.Fatalf("attempt %d: wait %s out of [%s, %s]", attempt, wait, max/2, max)
+			}
+		}
+	}
+
+	resp := &nethttp.Response{Header: nethttp.Header{"Retry-After": {"30"}}}
+	if wait := client.backoff(0, resp); wait != time.Second {
+		t.Errorf("Retry-After should be capped at retry_wait_max, got %s", wait)
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(100, nethttp.StatusServiceUnavailable, &calls)
+	defer server.Close()
+
+	b := breaker.NewBreakerWithOptions("test", &breaker.Options{BreakerConfig: proto.BreakerConfig{
+		MinRequests: 2,
+		OpenMs:      60000,
+	}})
+	client := newTestClient(server.URL, 5)
+	client.Use(BreakerMiddleware(b))
+
+	// 两次 503 后熔断，之后不再重试
+	_, err := client.Get(context.Background(), "/")
+	if !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls before the circuit opens, got %d", calls)
+	}
+}
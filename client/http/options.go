@@ -0,0 +1,103 @@
+package http
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认重试的状态码
+const defaultRetryStatus = "429,502,503,504"
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+type Options struct {
+	proto.HttpClientConfig
+
+	retryStatus map[int]bool
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.HttpClientConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	opts.parseRetryStatus()
+}
+
+// "502, 503, 504" => {502, 503, 504}
+func (opts *Options) parseRetryStatus() {
+	status := opts.RetryStatus
+	if status == "" {
+		status = defaultRetryStatus
+	}
+
+	opts.retryStatus = make(map[int]bool)
+	for _, s := range strings.Split(status, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || code < 100 || code > 599 {
+			panic("http client: invalid retry_status '" + opts.RetryStatus + "'")
+		}
+		opts.retryStatus[code] = true
+	}
+}
+
+// 单次调用的超时时间，包括重试和读取响应，0 表示不超时
+func (opt *Options) GetTimeout() time.Duration {
+	return time.Duration(opt.TimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetDialTimeout() time.Duration {
+	if opt.DialTimeoutMs <= 0 {
+		return time.Duration(3) * time.Second
+	}
+	return time.Duration(opt.DialTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetMaxIdleConns() int {
+	if opt.MaxIdleConns <= 0 {
+		return 100
+	}
+	return opt.MaxIdleConns
+}
+
+func (opt *Options) GetMaxIdleConnsPerHost() int {
+	if opt.MaxIdleConnsPerHost <= 0 {
+		return 16
+	}
+	return opt.MaxIdleConnsPerHost
+}
+
+func (opt *Options) GetIdleConnTimeout() time.Duration {
+	if opt.IdleConnTimeoutMs <= 0 {
+		return time.Duration(90) * time.Second
+	}
+	return time.Duration(opt.IdleConnTimeoutMs) * time.Millisecond
+}
+
+// 第一次重试前的等待时间，之后每次翻倍
+func (opt *Options) GetRetryWaitMin() time.Duration {
+	if opt.RetryWaitMinMs <= 0 {
+		return time.Duration(100) * time.Millisecond
+	}
+	return time.Duration(opt.RetryWaitMinMs) * time.Millisecond
+}
+
+func (opt *Options) GetRetryWaitMax() time.Duration {
+	if opt.RetryWaitMaxMs <= 0 {
+		return time.Duration(2) * time.Second
+	}
+	return time.Duration(opt.RetryWaitMaxMs) * time.Millisecond
+}
+
+func (opt *Options) IsRetryStatus(code int) bool {
+	return opt.retryStatus[code]
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const MIMEApplicationJSON = "application/json"
+
+// RequestOption 单个请求的设置，覆盖配置文件中的默认值
+type RequestOption func(o *requestOptions)
+
+type requestOptions struct {
+	timeout  time.Duration
+	retryMax int
+	header   http.Header
+	query    url.Values
+}
+
+// WithTimeout 设置请求的超时时间，包括重试和读取响应
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry 设置最大重试次数，0 表示不重试
+func WithRetry(retryMax int) RequestOption {
+	return func(o *requestOptions) {
+		o.retryMax = retryMax
+	}
+}
+
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(key, value)
+	}
+}
+
+// WithQuery 追加 query 参数，同名参数会被覆盖
+func WithQuery(query url.Values) RequestOption {
+	return func(o *requestOptions) {
+		for key, values := range query {
+			o.query[key] = values
+		}
+	}
+}
+
+func (c *Client) requestOptions(options []RequestOption) *requestOptions {
+	o := &requestOptions{
+		timeout:  c.opts.GetTimeout(),
+		retryMax: c.opts.RetryMax,
+		header:   make(http.Header),
+		query:    make(url.Values),
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// Response 已读取完 body 的响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+func (r *Response) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// StatusError JSON 请求的响应状态码不是 2xx
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	body := e.Body
+	if len(body) > 256 {
+		body = body[:256]
+	}
+	return fmt.Sprintf("http client: unexpected status %d: %s", e.StatusCode, bytes.TrimSpace(body))
+}
+
+// Request 发送请求并读取完整的响应，任何状态码都不作为错误
+// url 不包含 scheme 时拼接在 base_url 之后
+func (c *Client) Request(ctx context.Context, method, url string, body io.Reader, options ...RequestOption) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url(url), body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+func (c *Client) Get(ctx context.Context, url string, options ...RequestOption) (*Response, error) {
+	return c.Request(ctx, http.MethodGet, url, nil, options...)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body []byte, options ...RequestOption) (*Response, error) {
+	options = append([]RequestOption{WithHeader("Content-Type", contentType)}, options...)
+	return c.Request(ctx, http.MethodPost, url, bytes.NewReader(body), options...)
+}
+
+// PostForm 以 application/x-www-form-urlencoded 格式提交
+func (c *Client) PostForm(ctx context.Context, url string, data url.Values, options ...RequestOption) (*Response, error) {
+	return c.Post(ctx, url, "application/x-www-form-urlencoded", []byte(data.Encode()), options...)
+}
+
+// DoJSON 以 JSON 格式发送 in（为 nil 时不带请求体），响应为 2xx 时将 body 解析到 out（为 nil 时忽略）
+// 其他状态码返回 *StatusError
+func (c *Client) DoJSON(ctx context.Context, method, url string, in, out interface{}, options ...RequestOption) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		options = append([]RequestOption{WithHeader("Content-Type", MIMEApplicationJSON)}, options...)
+	}
+	options = append([]RequestOption{WithHeader("Accept", MIMEApplicationJSON)}, options...)
+
+	resp, err := c.Request(ctx, method, url, body, options...)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+	if out == nil || len(resp.Body) == 0 {
+		return nil
+	}
+	return resp.JSON(out)
+}
+
+func (c *Client) GetJSON(ctx context.Context, url string, out interface{}, options ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodGet, url, nil, out, options...)
+}
+
+func (c *Client) PostJSON(ctx context.Context, url string, in, out interface{}, options ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodPost, url, in, out, options...)
+}
+
+func (c *Client) PutJSON(ctx context.Context, url string, in, out interface{}, options ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodPut, url, in, out, options...)
+}
+
+func (c *Client) DeleteJSON(ctx context.Context, url string, out interface{}, options ...RequestOption) error {
+	return c.DoJSON(ctx, http.MethodDelete, url, nil, out, options...)
+}
+
+// "/users" => base_url + "/users"
+func (c *Client) url(path string) string {
+	if c.opts.BaseUrl == "" || strings.Contains(path, "://") {
+		return path
+	}
+	return strings.TrimRight(c.opts.BaseUrl, "/") + "/" + strings.TrimLeft(path, "/")
+}
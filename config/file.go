@@ -3,4 +3,12 @@ package config
 const (
 	DefaultAppConfigFile  = "app.ini"
 	DefaultHttpConfigFile = "http.ini"
+	DefaultTcpConfigFile  = "tcp.ini"
+	DefaultUdpConfigFile  = "udp.ini"
+	DefaultRpcConfigFile  = "rpc.ini"
+	DefaultLogConfigFile  = "log.ini"
+
+	DefaultRpcClientConfigFile  = "rpc_client.ini"
+	DefaultHttpClientConfigFile = "http_client.ini"
+	DefaultBreakerConfigFile    = "breaker.ini"
 )
@@ -0,0 +1,13 @@
+package proto
+
+type BreakerConfig struct {
+	WindowMs         int64
+	WindowBuckets    int
+	MinRequests      int
+	ErrorPercent     int
+	OpenMs           int64
+	HalfOpenRequests int
+
+	MaxConcurrent int
+	MaxWaitMs     int64
+}
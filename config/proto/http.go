@@ -1,14 +1,25 @@
 package proto
 
 type HttpConfig struct {
-	ServiceName     string
-	Addr            string
-	AccessLog       bool
-	AccessLogDir    string
-	AccessLogRotate string
-	ReadTimeoutMs   int64
-	WriteTimeoutMs  int64
-	MaxPostMemory   string
-	HttpsCertFile   string
-	HttpsKeyFile    string
+	ServiceName       string
+	Addr              string
+	AccessLog         bool
+	AccessLogDir      string
+	AccessLogRotate   string
+	AccessLogFormat   string
+	AccessLogFields   string
+	ReadTimeoutMs     int64
+	WriteTimeoutMs    int64
+	ShutdownTimeoutMs int64
+	MaxPostMemory     string
+	HttpsCertFile     string
+	HttpsKeyFile      string
+	GracefulRestart   bool
+	LogicPathStyle    string
+	LogicVerbRouting  bool
+
+	WebsocketMaxMessageSize int64
+	WebsocketReadTimeoutMs  int64
+	WebsocketWriteTimeoutMs int64
+	WebsocketCompression    bool
 }
@@ -0,0 +1,21 @@
+package proto
+
+type HttpClientConfig struct {
+	BaseUrl         string
+	UserAgent       string
+	AccessLog       bool
+	AccessLogDir    string
+	AccessLogRotate string
+
+	TimeoutMs           int64
+	DialTimeoutMs       int64
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeoutMs   int64
+
+	RetryMax        int
+	RetryWaitMinMs  int64
+	RetryWaitMaxMs  int64
+	RetryStatus     string
+	RetryAllMethods bool
+}
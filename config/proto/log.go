@@ -0,0 +1,28 @@
+package proto
+
+type LogConfig struct {
+	Level      string
+	Formatter  string
+	Prefix     string
+	ShowCaller bool
+
+	Dir    string
+	File   string
+	Sinks  string
+	Stdout bool
+
+	Rotate     string
+	MaxSizeMb  int64
+	MaxAgeDays int
+	MaxBackups int
+	Compress   bool
+
+	Async          bool
+	AsyncQueueSize int
+	AsyncBatchSize int
+	AsyncOverflow  string
+	AsyncDropLevel string
+
+	Loggers     string
+	DebugSignal bool
+}
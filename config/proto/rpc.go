@@ -0,0 +1,26 @@
+package proto
+
+type RpcConfig struct {
+	ServiceName       string
+	Addr              string
+	AccessLog         bool
+	AccessLogDir      string
+	AccessLogRotate   string
+	IdleTimeoutMs     int64
+	WriteTimeoutMs    int64
+	ShutdownTimeoutMs int64
+	MaxConnections    int
+	MaxPacketSize     int
+}
+
+type RpcClientConfig struct {
+	Addr                string
+	Codec               string
+	PoolSize            int
+	DialTimeoutMs       int64
+	CallTimeoutMs       int64
+	WriteTimeoutMs      int64
+	HeartbeatIntervalMs int64
+	HeartbeatMaxMissed  int
+	MaxPacketSize       int
+}
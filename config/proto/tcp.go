@@ -0,0 +1,14 @@
+package proto
+
+type TcpConfig struct {
+	ServiceName       string
+	Addr              string
+	AccessLog         bool
+	AccessLogDir      string
+	AccessLogRotate   string
+	IdleTimeoutMs     int64
+	WriteTimeoutMs    int64
+	ShutdownTimeoutMs int64
+	MaxConnections    int
+	MaxPacketSize     int
+}
@@ -0,0 +1,18 @@
+package proto
+
+type UdpConfig struct {
+	ServiceName       string
+	Addr              string
+	AccessLog         bool
+	AccessLogDir      string
+	AccessLogRotate   string
+	ReadBufferSize    int
+	WriteBufferSize   int
+	MaxPacketSize     int
+	Workers           int
+	QueueSize         int
+	RateLimit         float64
+	RateBurst         int
+	WriteTimeoutMs    int64
+	ShutdownTimeoutMs int64
+}
@@ -1,19 +1,75 @@
 package error
 
+import (
+	"strings"
+)
+
 type ErrorCode int
 
+// 通用错误码，与 HTTP 状态码保持一致
+// 业务错误码可自行定义，HTTP 服务中通过 RegisterErrorStatus 映射到状态码
+const (
+	CodeOK                 ErrorCode = 0
+	CodeBadRequest         ErrorCode = 400
+	CodeUnauthorized       ErrorCode = 401
+	CodeForbidden          ErrorCode = 403
+	CodeNotFound           ErrorCode = 404
+	CodeMethodNotAllowed   ErrorCode = 405
+	CodeConflict           ErrorCode = 409
+	CodeTooManyRequests    ErrorCode = 429
+	CodeInternal           ErrorCode = 500
+	CodeServiceUnavailable ErrorCode = 503
+)
+
+// 字段级错误信息，如参数校验失败
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type Error struct {
 	code    ErrorCode
 	message string
 	cause   error
 	file    string
 	line    int
+	fields  []FieldError
 }
 
 func (e *Error) Error() string {
+	if len(e.fields) == 0 {
+		return e.message
+	}
+
+	msg := make([]string, 0, len(e.fields))
+	for _, f := range e.fields {
+		msg = append(msg, f.Message)
+	}
+	return e.message + ": " + strings.Join(msg, "; ")
+}
+
+func (e *Error) Code() ErrorCode {
+	return e.code
+}
+
+func (e *Error) Message() string {
 	return e.message
 }
 
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Fields() []FieldError {
+	return e.fields
+}
+
+// 追加字段错误
+func (e *Error) AddField(field, message string) *Error {
+	e.fields = append(e.fields, FieldError{Field: field, Message: message})
+	return e
+}
+
 //
 func New(message string) *Error {
 	e := &Error{
@@ -35,6 +91,7 @@ func NewWithCode(code ErrorCode, message string) *Error {
 func Wrap(err error) *Error {
 	return &Error{
 		message: err.Error(),
+		cause:   err,
 	}
 }
 
@@ -42,5 +99,6 @@ func WrapWithCode(code ErrorCode, err error) *Error {
 	return &Error{
 		code:    code,
 		message: err.Error(),
+		cause:   err,
 	}
 }
@@ -0,0 +1,254 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 队列满时阻塞等待，不丢日志
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop 队列满时丢弃新日志
+	OverflowDrop
+	// OverflowDropBelowLevel 队列满时丢弃低于 DropLevel 的日志，其余阻塞等待
+	OverflowDropBelowLevel
+)
+
+// AsyncOptions 异步写入设置
+type AsyncOptions struct {
+	// 队列长度，默认 4096
+	QueueSize int
+	// 每次合并写入的最大条数，默认 256
+	BatchSize int
+	// 队列满时的处理方式，默认阻塞
+	Overflow OverflowPolicy
+	// Overflow 为 OverflowDropBelowLevel 时生效，如 log.WARN
+	DropLevel LogLevel
+}
+
+// 所有开启异步写入的 Logger，用于 FlushAll
+var asyncCores sync.Map
+
+type asyncEntry struct {
+	level LogLevel
+	text  []byte
+}
+
+// 有界环形队列，由后台 goroutine 批量写入输出
+type asyncWriter struct {
+	core *core
+	opts AsyncOptions
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+
+	queue       []asyncEntry
+	head, count int
+	writing     bool
+	closed      bool
+
+	// 上次写入后丢弃的条数，写入时输出一条警告
+	dropped uint64
+	// 累计丢弃的条数
+	droppedTotal uint64
+
+	done chan struct{}
+}
+
+func newAsyncWriter(c *core, opts AsyncOptions) *asyncWriter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 4096
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 256
+	}
+
+	w := &asyncWriter{
+		core:  c,
+		opts:  opts,
+		queue: make([]asyncEntry, opts.QueueSize),
+		done:  make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	w.idle = sync.NewCond(&w.mu)
+
+	go w.run()
+	return w
+}
+
+// 放入队列，已关闭时返回 false，由调用方同步写入
+func (w *asyncWriter) enqueue(level LogLevel, text []byte) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.count == len(w.queue) && !w.closed {
+		if w.opts.Overflow == OverflowDrop || (w.opts.Overflow == OverflowDropBelowLevel && level < w.opts.DropLevel) {
+			w.dropped++
+			atomic.AddUint64(&w.droppedTotal, 1)
+			return true
+		}
+		w.notFull.Wait()
+	}
+	if w.closed {
+		return false
+	}
+
+	// text 可能是调用方复用的 buffer（如 Write）
+	entry := asyncEntry{level: level, text: append([]byte(nil), text...)}
+	w.queue[(w.head+w.count)%len(w.queue)] = entry
+	w.count++
+	w.notEmpty.Signal()
+
+	return true
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	var batch []byte
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			return
+		}
+
+		batch = batch[:0]
+		if w.dropped > 0 {
+			text, err := w.core.formatter.Format(WARN, 0, fmt.Sprintf("log: queue full, dropped %d entries", w.dropped))
+			if err == nil {
+				batch = append(batch, text...)
+			}
+			w.dropped = 0
+		}
+		for n := 0; n < w.opts.BatchSize && w.count > 0; n++ {
+			entry := &w.queue[w.head]
+			batch = append(batch, entry.text...)
+			entry.text = nil
+			w.head = (w.head + 1) % len(w.queue)
+			w.count--
+		}
+		w.writing = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		w.core.mu.Lock()
+		w.core.writeOut(batch)
+		w.core.mu.Unlock()
+
+		w.mu.Lock()
+		w.writing = false
+		if w.count == 0 {
+			w.idle.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// 等待队列中已有的日志写入完成
+func (w *asyncWriter) flush() {
+	w.mu.Lock()
+	for w.count > 0 || w.writing {
+		w.idle.Wait()
+	}
+	w.mu.Unlock()
+}
+
+// 停止接收新日志，写完队列后返回
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		w.notEmpty.Broadcast()
+		w.notFull.Broadcast()
+	}
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// SetAsync 开启异步写入，日志格式化后放入有界队列，由后台 goroutine 批量写入输出
+// 需要在退出前调用 Close 或 Flush，Fatal、Panic 和服务关闭时会自动 Flush
+func (l *Logger) SetAsync(opts AsyncOptions) {
+	w := newAsyncWriter(l.core, opts)
+	if old := l.swapAsync(w); old != nil {
+		old.close()
+	}
+	asyncCores.Store(l.core, struct{}{})
+}
+
+// Dropped 异步写入时因队列已满丢弃的日志条数
+func (l *Logger) Dropped() uint64 {
+	if w := l.loadAsync(); w != nil {
+		return atomic.LoadUint64(&w.droppedTotal)
+	}
+	return 0
+}
+
+// Close 停止异步写入并写完队列中的日志，关闭日志文件和 AddSink 添加的 Logger
+// 之后的日志同步写入，文件在下次写入时重新打开
+func (l *Logger) Close() error {
+	for _, s := range l.loadSinks() {
+		_ = s.logger.Close()
+	}
+	if w := l.swapAsync(nil); w != nil {
+		w.close()
+	}
+	asyncCores.Delete(l.core)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 结束清理备份的 goroutine，之后再切割时重新启动
+	if l.millCh != nil {
+		close(l.millCh)
+		l.millCh = nil
+	}
+
+	if !l.opened {
+		return nil
+	}
+	return l.closeOutput()
+}
+
+func (l *Logger) loadAsync() *asyncWriter {
+	w, _ := l.async.Load().(*asyncWriter)
+	return w
+}
+
+func (l *Logger) swapAsync(w *asyncWriter) *asyncWriter {
+	l.asyncMu.Lock()
+	defer l.asyncMu.Unlock()
+
+	old := l.loadAsync()
+	l.async.Store(w)
+	return old
+}
+
+// FlushAll 写完所有异步 Logger 队列中的日志并同步到磁盘
+func FlushAll() {
+	asyncCores.Range(func(key, _ interface{}) bool {
+		l := &Logger{core: key.(*core)}
+		_ = l.Flush()
+		return true
+	})
+	_ = _log.Flush()
+}
+
+// ------------------------------------------------------------
+func SetAsync(opts AsyncOptions) {
+	_log.SetAsync(opts)
+}
+
+func Close() error {
+	return _log.Close()
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 关闭 release 之前阻塞所有写入
+type gateWriter struct {
+	started chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func newGateLogger(opts AsyncOptions) (*Logger, *gateWriter) {
+	w := newGateWriter()
+	l := NewLogger()
+	l.out = w
+	l.SetAsync(opts)
+
+	// 第一条日志被后台 goroutine 取出并阻塞在写入中，之后的日志留在队列里
+	l.Print("first")
+	<-w.started
+	return l, w
+}
+
+func TestAsyncOrderAndFlush(t *testing.T) {
+	l, buf := newBufferLogger()
+	l.SetAsync(AsyncOptions{QueueSize: 16, BatchSize: 4})
+	defer l.Close()
+
+	for i := 0; i < 1000; i++ {
+		l.Print(i)
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1000 {
+		t.Fatalf("expected 1000 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, " "+strconv.Itoa(i)) {
+			t.Fatalf("line %d out of order: %q", i, line)
+		}
+	}
+}
+
+func TestAsyncOverflowDrop(t *testing.T) {
+	l, w := newGateLogger(AsyncOptions{QueueSize: 2, Overflow: OverflowDrop})
+
+	l.Print("second")
+	l.Print("third")
+	l.Print("dropped")
+	if l.Dropped() != 1 {
+		t.Errorf("expected 1 dropped entry, got %d", l.Dropped())
+	}
+
+	close(w.release)
+	_ = l.Close()
+
+	out := w.buf.String()
+	if strings.Contains(out, "dropped\n") || !strings.Contains(out, "third") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if !strings.Contains(out, "[warn]: log: queue full, dropped 1 entries") {
+		t.Errorf("expected dropped warning, got %q", out)
+	}
+}
+
+func TestAsyncOverflowDropBelowLevel(t *testing.T) {
+	l, w := newGateLogger(AsyncOptions{QueueSize: 1, Overflow: OverflowDropBelowLevel, DropLevel: WARN})
+
+	l.Info("queued")
+	l.Info("dropped")
+
+	// 达到 DropLevel 的日志阻塞等待
+	done := make(chan struct{})
+	go func() {
+		l.Error("blocked")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("error entry should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	<-done
+	_ = l.Close()
+
+	out := w.buf.String()
+	if strings.Contains(out, "[info]: dropped") || !strings.Contains(out, "[error]: blocked") || !strings.Contains(out, "[info]: queued") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestAsyncClose(t *testing.T) {
+	l, w := newGateLogger(AsyncOptions{QueueSize: 4})
+	l.Print("second")
+
+	closed := make(chan struct{})
+	go func() {
+		_ = l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("close should wait for queued entries")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	<-closed
+	if !strings.HasSuffix(w.buf.String(), "second\n") {
+		t.Fatalf("expected queued entries to be written, got %q", w.buf.String())
+	}
+
+	// 关闭后同步写入
+	l.Print("sync")
+	if !strings.HasSuffix(w.buf.String(), "sync\n") {
+		t.Errorf("expected synchronous write after close, got %q", w.buf.String())
+	}
+}
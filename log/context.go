@@ -0,0 +1,17 @@
+package log
+
+import "context"
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// ContextWithRequestID 在 context 中保存请求 ID，用于在服务之间传递
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// 常用字段名，日志系统中按这些 key 检索
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+	FieldTraceID   = "trace_id"
+	FieldError     = "error"
+)
+
+// Field 日志字段，按添加顺序输出
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+type Fields map[string]interface{}
+
+// WithField 返回带有字段的子 Logger，子 Logger 与 l 共享输出和设置，如：
+//
+//	logger := log.WithField("order_id", id)
+//	logger.Info("paid")  // ... [info]: paid order_id=1001
+func (l *Logger) WithField(key string, value interface{}) *Logger {
+	return l.with([]Field{{Key: key, Value: value}})
+}
+
+// WithFields 与 WithField 相同，字段按 key 排序
+func (l *Logger) WithFields(fields Fields) *Logger {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]Field, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, Field{Key: key, Value: fields[key]})
+	}
+	return l.with(list)
+}
+
+// With 以 key, value 交替的方式添加字段，如 With("order_id", id, "amount", 100)
+// key 不是字符串时通过 fmt.Sprint 转换，缺少的 value 为 "(MISSING)"
+func (l *Logger) With(kv ...interface{}) *Logger {
+	list := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		var value interface{} = "(MISSING)"
+		if i+1 < len(kv) {
+			value = kv[i+1]
+		}
+		list = append(list, Field{Key: key, Value: value})
+	}
+	return l.with(list)
+}
+
+func (l *Logger) WithRequestID(id string) *Logger {
+	return l.WithField(FieldRequestID, id)
+}
+
+// WithUserID id 保留原始类型，JSON 格式中数字 ID 仍为数字
+func (l *Logger) WithUserID(id interface{}) *Logger {
+	return l.WithField(FieldUserID, id)
+}
+
+func (l *Logger) WithTraceID(id string) *Logger {
+	return l.WithField(FieldTraceID, id)
+}
+
+func (l *Logger) WithError(err error) *Logger {
+	return l.WithField(FieldError, err)
+}
+
+// WithContext 添加 context 中的请求 ID，没有时返回 l
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return l.WithRequestID(id)
+	}
+	return l
+}
+
+// 同名字段覆盖父 Logger 中的值，位置不变
+func (l *Logger) with(fields []Field) *Logger {
+	merged := make([]Field, len(l.fields), len(l.fields)+len(fields))
+	copy(merged, l.fields)
+
+next:
+	for _, field := range fields {
+		for i := range merged {
+			if merged[i].Key == field.Key {
+				merged[i].Value = field.Value
+				continue next
+			}
+		}
+		merged = append(merged, field)
+	}
+
+	return &Logger{core: l.core, fields: merged}
+}
+
+// ------------------------------------------------------------
+func WithField(key string, value interface{}) *Logger {
+	return _log.WithField(key, value)
+}
+
+func WithFields(fields Fields) *Logger {
+	return _log.WithFields(fields)
+}
+
+func With(kv ...interface{}) *Logger {
+	return _log.With(kv...)
+}
+
+func WithRequestID(id string) *Logger {
+	return _log.WithRequestID(id)
+}
+
+func WithUserID(id interface{}) *Logger {
+	return _log.WithUserID(id)
+}
+
+func WithTraceID(id string) *Logger {
+	return _log.WithTraceID(id)
+}
+
+func WithError(err error) *Logger {
+	return _log.WithError(err)
+}
+
+func WithContext(ctx context.Context) *Logger {
+	return _log.WithContext(ctx)
+}
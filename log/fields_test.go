@@ -0,0 +1,129 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newBufferLogger() (*Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	l := NewLogger()
+	l.out = buf
+	return l, buf
+}
+
+func TestTextFields(t *testing.T) {
+	l, buf := newBufferLogger()
+
+	child := l.WithField("order_id", 1001).With("note", "two words", "odd")
+	child.WithRequestID("req-1").Info("paid", 100)
+	l.Info("parent")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	want := `[info]: paid 100 order_id=1001 note="two words" odd=(MISSING) request_id=req-1`
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("expected suffix %q, got %q", want, lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[info]: parent") {
+		t.Errorf("parent should not carry child fields, got %q", lines[1])
+	}
+
+	// 子 Logger 共享级别设置
+	buf.Reset()
+	l.SetLevel(WARN)
+	child.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("child should follow parent level, got %q", buf.String())
+	}
+}
+
+func TestJsonFields(t *testing.T) {
+	l, buf := newBufferLogger()
+	l.SetJsonFormatter()
+
+	l.WithFields(Fields{"user_id": 7, "message": "shadowed"}).
+		WithTraceID("trace-1").
+		WithError(errors.New("timeout")).
+		WithContext(ContextWithRequestID(context.Background(), "req-2")).
+		WithUserID(8).
+		Warnf("retry %d", 3)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+
+	want := map[string]interface{}{
+		"level":          "warn",
+		"message":        "retry 3",
+		"fields.message": "shadowed",
+		FieldUserID:      float64(8),
+		FieldTraceID:     "trace-1",
+		FieldError:       "timeout",
+		FieldRequestID:   "req-2",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, entry[key])
+		}
+	}
+
+	if l.WithContext(context.Background()) != l {
+		t.Error("WithContext without request id should return the same logger")
+	}
+}
+
+// 只实现 Formatter 的旧格式化器
+type plainFormatter struct {
+	calls []string
+}
+
+func (f *plainFormatter) Format(level LogLevel, dep int, v string) ([]byte, error) {
+	f.calls = append(f.calls, getCaller(dep))
+	return []byte(v), nil
+}
+
+func TestFormatterWithoutFields(t *testing.T) {
+	l, buf := newBufferLogger()
+	f := new(plainFormatter)
+	l.formatter = f
+
+	l.WithField("order_id", 1001).Info("paid")
+	l.Info("plain")
+
+	if got, want := buf.String(), "paid order_id=1001\nplain\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	for _, caller := range f.calls {
+		if !strings.Contains(caller, "fields_test.go:") {
+			t.Errorf("caller should be the test file, got %q", caller)
+		}
+	}
+}
+
+func TestCallerWithFields(t *testing.T) {
+	for _, useJson := range []bool{false, true} {
+		l, buf := newBufferLogger()
+		l.ShowCaller()
+		if useJson {
+			l.SetJsonFormatter()
+		}
+
+		l.Info("plain")
+		l.WithField("k", "v").Infof("fields")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		for _, line := range lines {
+			if !strings.Contains(line, "fields_test.go:") {
+				t.Errorf("json=%v: caller should be the test file, got %q", useJson, line)
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,13 @@ type Formatter interface {
 	Format(level LogLevel, dep int, v string) (b []byte, err error)
 }
 
+// FieldsFormatter 可以输出 WithField 等附加字段的 Formatter
+// 只实现 Formatter 时，字段以 key=value 的形式追加在消息之后再调用 Format
+type FieldsFormatter interface {
+	Formatter
+	FormatFields(level LogLevel, dep int, v string, fields []Field) (b []byte, err error)
+}
+
 // TextFormatter 文本
 type TextFormatter struct {
 	logger *Logger
@@ -25,6 +33,11 @@ func NewTextFormatter(logger *Logger) Formatter {
 }
 
 func (f *TextFormatter) Format(level LogLevel, dep int, v string) (b []byte, err error) {
+	return f.FormatFields(level, dep+1, v, nil)
+}
+
+// 字段以 key=value 的形式追加在消息之后
+func (f *TextFormatter) FormatFields(level LogLevel, dep int, v string, fields []Field) (b []byte, err error) {
 	now := time.Now()
 
 	var text bytes.Buffer
@@ -40,10 +53,14 @@ func (f *TextFormatter) Format(level LogLevel, dep int, v string) (b []byte, err
 		text.WriteString("[" + levels[level] + "]: ")
 	}
 
-	text.WriteString(v)
+	if len(fields) > 0 {
+		text.WriteString(appendTextFields(v, fields))
+	} else {
+		text.WriteString(v)
 
-	if len(v) == 0 || v[len(v)-1] != '\n' {
-		text.WriteString("\n")
+		if len(v) == 0 || v[len(v)-1] != '\n' {
+			text.WriteString("\n")
+		}
 	}
 
 	return text.Bytes(), nil
@@ -61,7 +78,12 @@ func NewJsonFormatter(l *Logger) *JsonFormatter {
 }
 
 func (f *JsonFormatter) Format(level LogLevel, dep int, v string) (b []byte, err error) {
-	things := make(map[string]interface{})
+	return f.FormatFields(level, dep+1, v, nil)
+}
+
+// 字段作为顶层的 key 输出，与 time、message 等内置 key 冲突时加上 "fields." 前缀
+func (f *JsonFormatter) FormatFields(level LogLevel, dep int, v string, fields []Field) (b []byte, err error) {
+	things := make(map[string]interface{}, len(fields)+4)
 
 	msgLength := len(v)
 	if msgLength > 0 && v[msgLength-1] == '\n' {
@@ -79,6 +101,15 @@ func (f *JsonFormatter) Format(level LogLevel, dep int, v string) (b []byte, err
 		things["file"] = getCaller(dep)
 	}
 
+	for _, field := range fields {
+		key := field.Key
+		switch key {
+		case "time", "message", "level", "file":
+			key = "fields." + key
+		}
+		things[key] = jsonValue(field.Value)
+	}
+
 	thingsBuffer := &bytes.Buffer{}
 
 	encoder := json.NewEncoder(thingsBuffer)
@@ -89,6 +120,46 @@ func (f *JsonFormatter) Format(level LogLevel, dep int, v string) (b []byte, err
 	return thingsBuffer.Bytes(), nil
 }
 
+// "msg\n" => "msg key=value\n"
+func appendTextFields(v string, fields []Field) string {
+	var text strings.Builder
+	text.WriteString(strings.TrimSuffix(v, "\n"))
+	for _, field := range fields {
+		text.WriteString(" " + field.Key + "=" + textValue(field.Value))
+	}
+	text.WriteString("\n")
+
+	return text.String()
+}
+
+// 字符串包含空格、引号或 "=" 时加上引号
+func textValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case string:
+		s = value
+	case error:
+		s = value.Error()
+	default:
+		s = fmt.Sprint(value)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// error 直接序列化为 {}，转换为错误信息
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		if _, ok := v.(json.Marshaler); !ok {
+			return err.Error()
+		}
+	}
+	return v
+}
+
 func getCaller(dep int) string {
 	file, line, ok, _ := "--", 0, true, "--"
 	_, file, line, ok = runtime.Caller(dep)
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -53,14 +54,23 @@ var _log *Logger
 
 func init() {
 	_log = NewLogger()
+	Register(DefaultName, _log)
 	log.Print()
 }
 
+// Logger 日志，WithField 等方法创建的子 Logger 与父 Logger 共享输出和设置
 type Logger struct {
+	*core
+
+	// 附加在每条日志上的字段，见 WithField
+	fields []Field
+}
+
+type core struct {
 	mu sync.Mutex
 
-	// 日志级别, 默认 "log.Debug"
-	level LogLevel
+	// 日志级别, 默认 "log.Debug"，运行时可以修改，原子读写
+	level int32
 
 	// 日志切割方式，支持按 "D"、"H"，即 "按天"、"按小时" 进行切割
 	// 默认不切割，可以通过 "log.SetRotateHourly()" 和 "log.SetRotateDaily()" 修改
@@ -71,6 +81,17 @@ type Logger struct {
 	// 文件后缀，当指定切割方式时生效
 	dir, file, suffix string
 
+	// 文件是否已打开，修改文件名或关闭后在下次写入时重新打开
+	opened bool
+
+	// 按大小切割和清理备份，见 SetMaxSize
+	size       int64
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	millCh     chan struct{}
+
 	// 前缀信息
 	prefix string
 
@@ -82,27 +103,44 @@ type Logger struct {
 
 	// 输出格式，支持 "text" 和 "json" 格式输出
 	formatter Formatter
+
+	// 异步写入，见 SetAsync
+	async   atomic.Value
+	asyncMu sync.Mutex
+
+	// 按级别输出到其他 Logger，见 AddSink
+	sinks atomic.Value
 }
 
 func NewLogger() *Logger {
-	l := &Logger{
-		level:      DEBUG,
+	l := &Logger{core: &core{
+		level:      int32(DEBUG),
 		rotateType: RotateNone,
 		out:        os.Stdout,
-	}
+	}}
 	l.formatter = NewTextFormatter(l)
 
 	return l
 }
 
+// SetLevel 设置日志级别，可以在运行时并发调用
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+func (l *Logger) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32(&l.level))
 }
 
 func (l *Logger) ShowCaller() {
 	l.showCaller = true
 }
 
+// Deprecated: 使用 ShowCaller
+func (l *Logger) ShowFile() {
+	l.ShowCaller()
+}
+
 func (l *Logger) SetPrefix(prefix string) {
 	l.prefix = prefix
 }
@@ -126,7 +164,7 @@ func (l *Logger) SetJsonFormatter() {
 func (l *Logger) SetOutputDir(dir string) {
 	if _, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
-			if mkErr := os.Mkdir(dir, 0644); mkErr != nil {
+			if mkErr := os.MkdirAll(dir, 0755); mkErr != nil {
 				panic(mkErr.Error())
 			}
 		} else {
@@ -134,7 +172,11 @@ func (l *Logger) SetOutputDir(dir string) {
 		}
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.dir = dir
+	l.opened = false
 }
 
 func (l *Logger) SetOutputByName(name string) {
@@ -142,57 +184,119 @@ func (l *Logger) SetOutputByName(name string) {
 	defer l.mu.Unlock()
 
 	l.file = name
+	l.opened = false
 }
 
 func (l *Logger) log(level LogLevel, dep int, v ...interface{}) {
-	if l.level > level {
+	if l.Level() > level {
 		return
 	}
 
-	text, err := l.formatter.Format(level, dep, fmt.Sprintln(v...))
+	text, err := l.format(level, dep, fmt.Sprintln(v...))
 	if err != nil {
 		return
 	}
 
-	l.write(text)
+	l.write(level, text)
 }
 
 func (l *Logger) logf(level LogLevel, dep int, f string, v ...interface{}) {
-	if l.level > level {
+	if l.Level() > level {
 		return
 	}
 
-	text, err := l.formatter.Format(level, dep, fmt.Sprintf(f, v...))
+	text, err := l.format(level, dep, fmt.Sprintf(f, v...))
 	if err != nil {
 		return
 	}
 
-	l.write(text)
+	l.write(level, text)
 }
 
-func (l *Logger) write(text []byte) {
+// 带有字段时优先使用 FieldsFormatter
+func (l *Logger) format(level LogLevel, dep int, v string) ([]byte, error) {
+	if len(l.fields) == 0 {
+		return l.formatter.Format(level, dep+1, v)
+	}
+	if f, ok := l.formatter.(FieldsFormatter); ok {
+		return f.FormatFields(level, dep+1, v, l.fields)
+	}
+	return l.formatter.Format(level, dep+1, appendTextFields(v, l.fields))
+}
+
+// Write 实现 io.Writer，内容原样写入，不经过格式化和级别过滤
+func (l *Logger) Write(p []byte) (int, error) {
+	l.write(PRINT, p)
+	return len(p), nil
+}
+
+func (l *Logger) write(level LogLevel, text []byte) {
+	if l.writeSinks(level, text) {
+		return
+	}
+	if w := l.loadAsync(); w != nil && w.enqueue(level, text) {
+		return
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	l.rotate()
+	l.writeOut(text)
+}
+
+// 需要持有锁
+func (c *core) writeOut(text []byte) {
+	c.rotate(len(text))
 
-	if _, err := l.out.Write(text); err != nil {
+	n, err := c.out.Write(text)
+	c.size += int64(n)
+	if err != nil {
 		fmt.Println("WErr:", err.Error())
 	}
 }
 
-func (l *Logger) rotate() {
+// Flush 写完异步队列中的日志，并将已写入的日志同步到磁盘，同步仅对文件输出生效
+func (l *Logger) Flush() error {
+	for _, s := range l.loadSinks() {
+		if err := s.logger.Flush(); err != nil {
+			return err
+		}
+	}
+	if w := l.loadAsync(); w != nil {
+		w.flush()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if l.file == "" {
+		return nil
+	}
+
+	if f, ok := l.out.(*os.File); ok {
+		return f.Sync()
+	}
+
+	return nil
+}
+
+// 按时间切割时切换到新的文件，超过 maxSize 时将当前文件重命名为备份
+func (c *core) rotate(n int) {
+	if c.file == "" {
 		return
 	}
 
-	suffix := l.genSuffix()
-	if l.suffix == "" || (l.suffix != suffix && l.rotateType != RotateNone) {
-		l.suffix = suffix
-		l.setOutput()
+	suffix := c.genSuffix()
+	if !c.opened || (c.suffix != suffix && c.rotateType != RotateNone) {
+		c.suffix = suffix
+		c.setOutput()
+		c.mill()
+		return
 	}
 
-	return
+	if c.maxSize > 0 && c.size > 0 && c.size+int64(n) > c.maxSize {
+		c.rotateBySize()
+	}
 }
 
 func (l *Logger) Debug(v ...interface{}) {
@@ -230,22 +334,29 @@ func (l *Logger) Errorf(f string, v ...interface{}) {
 func (l *Logger) Panic(v ...interface{}) {
 	msg := concat(v...)
 	l.log(PANIC, 4, v...)
+	_ = l.Flush()
 	panic(msg)
 }
 
 func (l *Logger) Panicf(f string, v ...interface{}) {
 	msg := concat(v...)
 	l.logf(PANIC, 4, f, v...)
+	_ = l.Flush()
 	panic(msg)
 }
 
+// Fatal 写完所有异步 Logger 的日志后退出
 func (l *Logger) Fatal(v ...interface{}) {
 	l.log(FATAL, 4, v...)
+	_ = l.Flush()
+	FlushAll()
 	os.Exit(1)
 }
 
 func (l *Logger) Fatalf(f string, v ...interface{}) {
 	l.logf(FATAL, 4, f, v...)
+	_ = l.Flush()
+	FlushAll()
 	os.Exit(1)
 }
 
@@ -257,12 +368,12 @@ func (l *Logger) Printf(f string, v ...interface{}) {
 	l.logf(PRINT, 4, f, v...)
 }
 
-func (l *Logger) genSuffix() string {
+func (c *core) genSuffix() string {
 	var suffix string
 
-	if l.rotateType == RotateHourly {
+	if c.rotateType == RotateHourly {
 		suffix = time.Now().Format(SuffixFormatForHour)
-	} else if l.rotateType == RotateDaily {
+	} else if c.rotateType == RotateDaily {
 		suffix = time.Now().Format(SuffixFormatForDay)
 	}
 
@@ -277,20 +388,40 @@ func concat(msg ...interface{}) string {
 	return strings.Join(buf, " ")
 }
 
-func (l *Logger) setOutput() {
-	var fileName string
-	if l.rotateType == "" {
-		fileName = filepath.Join(l.dir, l.file)
-	} else {
-		fileName = filepath.Join(l.dir, l.file) + "." + l.suffix
+// 当前写入的文件
+func (c *core) fileName() string {
+	if c.rotateType == "" {
+		return filepath.Join(c.dir, c.file)
 	}
+	return filepath.Join(c.dir, c.file) + "." + c.suffix
+}
 
-	h, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
+// 打开新的文件并关闭之前的文件
+func (c *core) setOutput() {
+	h, err := os.OpenFile(c.fileName(), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	l.out = h
+	_ = c.closeOutput()
+	c.out = h
+	c.opened = true
+
+	c.size = 0
+	if info, err := h.Stat(); err == nil {
+		c.size = info.Size()
+	}
+}
+
+// 关闭日志文件，下次写入时重新打开，需要持有锁
+func (c *core) closeOutput() error {
+	f, ok := c.out.(*os.File)
+	c.out = nil
+	c.opened = false
+	if !ok || f == os.Stdout || f == os.Stderr {
+		return nil
+	}
+	return f.Close()
 }
 
 // ------------------------------------------------------------
@@ -326,6 +457,10 @@ func SetOutputByName(name string) {
 	_log.SetOutputByName(name)
 }
 
+func Flush() error {
+	return _log.Flush()
+}
+
 func Debug(v ...interface{}) {
 	_log.Debug(v...)
 }
@@ -0,0 +1,238 @@
+package log
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"strings"
+	"time"
+)
+
+// Options 日志配置，如：
+//
+//	level = info
+//	formatter = json
+//	dir = logs
+//	; 文件名:级别范围[:切割方式]，每个文件单独切割
+//	sinks = access.log:print, info.log:debug-warn, error.log:error-fatal:D
+//	; 同时输出到标准输出
+//	stdout = true
+//	rotate = H
+//	max_size_mb = 512
+//	max_age_days = 7
+//	compress = true
+//	; 异步写入，队列满时丢弃低于 warn 的日志
+//	async = true
+//	async_overflow = drop_below_level
+//	async_drop_level = warn
+//	; 其他 Logger 的名称，配置文件为 log_<name>.ini，通过 log.Get(name) 获取
+//	loggers = access, audit
+//	; 收到 SIGUSR1 时切换到 debug 级别
+//	debug_signal = true
+type Options struct {
+	proto.LogConfig
+
+	parsed bool
+	level  LogLevel
+	async  AsyncOptions
+	sinks  []sinkOptions
+}
+
+type sinkOptions struct {
+	file     string
+	min, max LogLevel
+	rotate   LogRotateType
+}
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.LogConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	opts.parse()
+}
+
+func (opts *Options) parse() {
+	opts.parsed = true
+
+	var err error
+	opts.level = DEBUG
+	if opts.Level != "" {
+		if opts.level, err = ParseLevel(opts.Level); err != nil {
+			panic(err)
+		}
+	}
+
+	switch opts.Formatter {
+	case "", "text", "json":
+	default:
+		panic("log: invalid formatter '" + opts.Formatter + "'")
+	}
+
+	opts.parseAsync()
+	opts.parseSinks()
+}
+
+func (opts *Options) parseAsync() {
+	opts.async = AsyncOptions{
+		QueueSize: opts.AsyncQueueSize,
+		BatchSize: opts.AsyncBatchSize,
+	}
+
+	switch opts.AsyncOverflow {
+	case "", "block":
+		opts.async.Overflow = OverflowBlock
+	case "drop":
+		opts.async.Overflow = OverflowDrop
+	case "drop_below_level":
+		opts.async.Overflow = OverflowDropBelowLevel
+		opts.async.DropLevel = WARN
+		if opts.AsyncDropLevel != "" {
+			level, err := ParseLevel(opts.AsyncDropLevel)
+			if err != nil {
+				panic(err)
+			}
+			opts.async.DropLevel = level
+		}
+	default:
+		panic("log: invalid async_overflow '" + opts.AsyncOverflow + "'")
+	}
+}
+
+// 只配置 file 时等同于 "file:debug-print"
+func (opts *Options) parseSinks() {
+	opts.sinks = nil
+
+	rotate := parseRotate(opts.Rotate)
+	if opts.File != "" {
+		opts.sinks = append(opts.sinks, sinkOptions{file: opts.File, min: DEBUG, max: PRINT, rotate: rotate})
+	}
+
+	for _, spec := range strings.Split(opts.Sinks, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			panic("log: invalid sink '" + spec + "'")
+		}
+
+		min, max, err := parseLevelRange(parts[1])
+		if err != nil {
+			panic(err)
+		}
+
+		s := sinkOptions{file: parts[0], min: min, max: max, rotate: rotate}
+		if len(parts) == 3 {
+			s.rotate = parseRotate(parts[2])
+		}
+		opts.sinks = append(opts.sinks, s)
+	}
+}
+
+func parseRotate(rotate string) LogRotateType {
+	switch rotate {
+	case "":
+		return RotateNone
+	case "D", "d", "day", "daily":
+		return RotateDaily
+	case "H", "h", "hour", "hourly":
+		return RotateHourly
+	}
+	panic("log: invalid rotate '" + rotate + "'")
+}
+
+func (opt *Options) GetMaxSize() int64 {
+	return opt.MaxSizeMb << 20
+}
+
+func (opt *Options) GetMaxAge() time.Duration {
+	return time.Duration(opt.MaxAgeDays) * 24 * time.Hour
+}
+
+// NewLoggerByConfig 从配置文件创建 Logger，配置错误时 panic
+func NewLoggerByConfig(file string) *Logger {
+	return NewLoggerWithOptions(newOptions(file))
+}
+
+// NewLoggerWithOptions 按配置创建 Logger，没有配置文件时输出到标准输出
+func NewLoggerWithOptions(opts *Options) *Logger {
+	l := NewLogger()
+	l.applyOptions(opts)
+
+	return l
+}
+
+func (l *Logger) applyOptions(opts *Options) {
+	if !opts.parsed {
+		opts.parse()
+	}
+
+	l.SetLevel(opts.level)
+	if opts.Formatter == "json" {
+		l.SetJsonFormatter()
+	} else {
+		l.SetTextFormatter()
+	}
+	if opts.Prefix != "" {
+		l.SetPrefix(opts.Prefix)
+	}
+	if opts.ShowCaller {
+		l.ShowCaller()
+	}
+
+	if len(opts.sinks) == 0 {
+		if opts.Async {
+			l.SetAsync(opts.async)
+		}
+		return
+	}
+
+	for _, s := range opts.sinks {
+		out := NewLogger()
+		if opts.Dir != "" {
+			out.SetOutputDir(opts.Dir)
+		}
+		out.SetOutputByName(s.file)
+		out.rotateType = s.rotate
+		out.SetMaxSize(opts.GetMaxSize())
+		out.SetMaxAge(opts.GetMaxAge())
+		out.SetMaxBackups(opts.MaxBackups)
+		out.SetCompress(opts.Compress)
+		if opts.Async {
+			out.SetAsync(opts.async)
+		}
+
+		l.AddSink(s.min, s.max, out)
+	}
+
+	if opts.Stdout {
+		l.AddSink(DEBUG, PRINT, NewLogger())
+	}
+}
+
+// ------------------------------------------------------------
+// InitByConfig 按配置文件设置默认 Logger，并创建 loggers 中配置的 Logger，只需在启动时调用一次
+func InitByConfig(file string) {
+	opts := newOptions(file)
+	_log.applyOptions(opts)
+
+	for _, name := range strings.Split(opts.Loggers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			Register(name, NewLoggerByConfig("log_"+name))
+		}
+	}
+
+	if opts.DebugSignal {
+		EnableDebugSignal()
+	}
+}
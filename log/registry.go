@@ -0,0 +1,124 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+)
+
+// DefaultName 默认 Logger 的名称
+const DefaultName = "default"
+
+// 按名称注册的 Logger，用于运行时修改级别
+var registry = struct {
+	sync.RWMutex
+	loggers map[string]*Logger
+}{loggers: make(map[string]*Logger)}
+
+// 开启调试前各 Logger 的级别
+var debugState = struct {
+	sync.Mutex
+	on    bool
+	saved map[string]LogLevel
+}{}
+
+var debugSignalOnce sync.Once
+
+// Register 按名称注册 Logger，同名时替换，默认 Logger 注册为 "default"
+func Register(name string, l *Logger) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.loggers[name] = l
+}
+
+// Get 获取注册的 Logger，不存在时返回 nil
+func Get(name string) *Logger {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return registry.loggers[name]
+}
+
+// Names 所有注册的 Logger 名称，按字母排序
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.loggers))
+	for name := range registry.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLevelByName 修改注册的 Logger 的级别，开启调试时在关闭调试后生效
+func SetLevelByName(name string, level LogLevel) error {
+	l := Get(name)
+	if l == nil {
+		return fmt.Errorf("log: unknown logger '%s'", name)
+	}
+
+	debugState.Lock()
+	defer debugState.Unlock()
+
+	if debugState.on {
+		debugState.saved[name] = level
+		return nil
+	}
+	l.SetLevel(level)
+	return nil
+}
+
+// ToggleDebug 将所有注册的 Logger 切换到 DEBUG 级别，再次调用时恢复原来的级别
+// 返回切换后是否处于调试状态
+func ToggleDebug() bool {
+	debugState.Lock()
+	defer debugState.Unlock()
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	if debugState.on {
+		for name, level := range debugState.saved {
+			if l, ok := registry.loggers[name]; ok {
+				l.SetLevel(level)
+			}
+		}
+		debugState.on, debugState.saved = false, nil
+		return false
+	}
+
+	debugState.saved = make(map[string]LogLevel, len(registry.loggers))
+	for name, l := range registry.loggers {
+		debugState.saved[name] = l.Level()
+		l.SetLevel(DEBUG)
+	}
+	debugState.on = true
+	return true
+}
+
+// EnableDebugSignal 收到 SIGUSR1 时调用 ToggleDebug，不支持的平台上不生效，多次调用只会执行一次
+func EnableDebugSignal() {
+	if debugSignal == nil {
+		return
+	}
+
+	debugSignalOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, debugSignal)
+
+		go func() {
+			for range ch {
+				if ToggleDebug() {
+					_log.Print("log: debug enabled by signal")
+				} else {
+					_log.Print("log: debug disabled by signal")
+				}
+			}
+		}()
+	})
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInitByConfig(t *testing.T) {
+	InitByConfig("log_init")
+
+	audit := Get("audit")
+	if audit == nil {
+		t.Fatalf("expected audit logger to be registered, got %v", Names())
+	}
+	defer audit.Close()
+
+	if audit.Level() != WARN {
+		t.Errorf("expected warn, got %s", audit.Level())
+	}
+	if _, ok := audit.formatter.(*JsonFormatter); !ok {
+		t.Errorf("expected json formatter, got %T", audit.formatter)
+	}
+	if w := audit.loadAsync(); w == nil || w.opts.Overflow != OverflowDropBelowLevel || w.opts.DropLevel != WARN {
+		t.Errorf("unexpected async writer %+v", w)
+	}
+	if Get("missing") != nil {
+		t.Error("expected nil for unknown logger")
+	}
+}
+
+func TestToggleDebug(t *testing.T) {
+	a, b := NewLogger(), NewLogger()
+	a.SetLevel(INFO)
+	b.SetLevel(ERROR)
+	Register("toggle.a", a)
+	Register("toggle.b", b)
+	defer func() {
+		registry.Lock()
+		delete(registry.loggers, "toggle.a")
+		delete(registry.loggers, "toggle.b")
+		registry.Unlock()
+	}()
+
+	if !ToggleDebug() || a.Level() != DEBUG || b.Level() != DEBUG {
+		t.Fatalf("expected debug level, got %s %s", a.Level(), b.Level())
+	}
+
+	// 调试期间修改的级别在关闭调试后生效
+	if err := SetLevelByName("toggle.b", WARN); err != nil {
+		t.Fatal(err)
+	}
+	if b.Level() != DEBUG {
+		t.Errorf("expected debug while toggled, got %s", b.Level())
+	}
+
+	if ToggleDebug() || a.Level() != INFO || b.Level() != WARN {
+		t.Fatalf("expected levels to be restored, got %s %s", a.Level(), b.Level())
+	}
+
+	if err := SetLevelByName("toggle.missing", DEBUG); err == nil {
+		t.Error("expected error for unknown logger")
+	}
+}
+
+func TestSetLevelConcurrent(t *testing.T) {
+	l, _ := newBufferLogger()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.SetLevel(LogLevel(j % 3))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Debug("message")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,244 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const compressSuffix = ".gz"
+
+// SetMaxSize 单个文件的最大字节数，超过后将当前文件重命名为编号递增的备份，如 app.log.1、app.log.2
+// 可以与按时间切割同时使用，如 app.log.2020010215.1；0 表示不限制
+func (l *Logger) SetMaxSize(size int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxSize = size
+}
+
+// SetMaxAge 删除修改时间超过 age 的备份，包括按时间切割产生的文件；0 表示不删除
+func (l *Logger) SetMaxAge(age time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxAge = age
+}
+
+// SetMaxBackups 最多保留的备份数，超出时删除最旧的；0 表示不限制
+func (l *Logger) SetMaxBackups(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxBackups = n
+}
+
+// SetCompress 在后台将备份压缩为 gzip 格式
+func (l *Logger) SetCompress(compress bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.compress = compress
+}
+
+// 需要持有锁
+func (c *core) rotateBySize() {
+	name := c.fileName()
+	if err := c.closeOutput(); err != nil {
+		fmt.Println("WErr:", err.Error())
+	}
+
+	if err := os.Rename(name, nextBackupName(name)); err != nil {
+		fmt.Println("WErr:", err.Error())
+	}
+
+	c.setOutput()
+	c.mill()
+}
+
+// name.N 或 name.N.gz 中最大的 N 加一
+func nextBackupName(name string) string {
+	prefix := filepath.Base(name) + "."
+	max := 0
+
+	files, _ := ioutil.ReadDir(filepath.Dir(name))
+	for _, f := range files {
+		n := strings.TrimSuffix(f.Name(), compressSuffix)
+		if !strings.HasPrefix(n, prefix) {
+			continue
+		}
+		if i, err := strconv.Atoi(n[len(prefix):]); err == nil && i > max {
+			max = i
+		}
+	}
+
+	return name + "." + strconv.Itoa(max+1)
+}
+
+// 通知后台 goroutine 清理和压缩备份，需要持有锁
+func (c *core) mill() {
+	if c.maxAge <= 0 && c.maxBackups <= 0 && !c.compress {
+		return
+	}
+
+	if c.millCh == nil {
+		c.millCh = make(chan struct{}, 1)
+		go c.millRun(c.millCh)
+	}
+
+	select {
+	case c.millCh <- struct{}{}:
+	default:
+	}
+}
+
+// Close 时关闭 ch 结束
+func (c *core) millRun(ch chan struct{}) {
+	for range ch {
+		c.mu.Lock()
+		dir, file, active := c.dir, c.file, c.fileName()
+		maxAge, maxBackups, compress := c.maxAge, c.maxBackups, c.compress
+		c.mu.Unlock()
+
+		if err := millBackups(dir, file, active, maxAge, maxBackups, compress); err != nil {
+			fmt.Println("WErr:", err.Error())
+		}
+	}
+}
+
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
+
+// 备份为 file 加上时间后缀和编号，如 app.log.2020010215、app.log.2020010215.1、app.log.1，可以带有 .gz
+func isBackupName(name, file string) bool {
+	if !strings.HasPrefix(name, file+".") {
+		return false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(name[len(file)+1:], compressSuffix), ".")
+	switch len(parts) {
+	case 1:
+		return isTimeSuffix(parts[0]) || isBackupNumber(parts[0])
+	case 2:
+		return isTimeSuffix(parts[0]) && isBackupNumber(parts[1])
+	}
+	return false
+}
+
+func isTimeSuffix(s string) bool {
+	for _, layout := range []string{SuffixFormatForHour, SuffixFormatForDay} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isBackupNumber(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && strconv.Itoa(n) == s
+}
+
+// 备份为 dir 下除当前文件外符合 isBackupName 的文件，按修改时间从新到旧清理
+func millBackups(dir, file, active string, maxAge time.Duration, maxBackups int, compress bool) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []backupFile
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || name == filepath.Base(active) || !isBackupName(name, file) {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), modTime: f.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	var firstErr error
+	deadline := time.Now().Add(-maxAge)
+	for i, backup := range backups {
+		if (maxBackups > 0 && i >= maxBackups) || (maxAge > 0 && backup.modTime.Before(deadline)) {
+			if err := os.Remove(backup.path); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if compress && !strings.HasSuffix(backup.path, compressSuffix) {
+			if err := compressFile(backup.path, backup.modTime); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// 压缩为 path.gz 后删除原文件，保留修改时间用于清理排序
+func compressFile(path string, modTime time.Time) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstPath := path + compressSuffix
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+
+	_ = os.Chtimes(dstPath, modTime, modTime)
+	return os.Remove(path)
+}
+
+// ------------------------------------------------------------
+func SetMaxSize(size int64) {
+	_log.SetMaxSize(size)
+}
+
+func SetMaxAge(age time.Duration) {
+	_log.SetMaxAge(age)
+}
+
+func SetMaxBackups(n int) {
+	_log.SetMaxBackups(n)
+}
+
+func SetCompress(compress bool) {
+	_log.SetCompress(compress)
+}
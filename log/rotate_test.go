@@ -0,0 +1,219 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFileLogger(t *testing.T) (*Logger, string) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	l := NewLogger()
+	l.SetOutputDir(dir)
+	l.SetOutputByName("app.log")
+	t.Cleanup(func() { _ = l.Close() })
+	return l, dir
+}
+
+func listDir(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 等待后台清理完成
+func waitFor(t *testing.T, dir string, want []string) {
+	deadline := time.Now().Add(2 * time.Second)
+	for strings.Join(listDir(t, dir), ",") != strings.Join(want, ",") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v after cleanup, got %v", want, listDir(t, dir))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func openFiles() int {
+	files, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(files)
+}
+
+func TestRotateBySize(t *testing.T) {
+	l, dir := newFileLogger(t)
+	l.SetMaxSize(100)
+
+	before := openFiles()
+	for i := 0; i < 20; i++ {
+		l.Print(strings.Repeat("x", 10))
+	}
+	if before >= 0 && openFiles() > before+1 {
+		t.Errorf("superseded files should be closed, open files %d -> %d", before, openFiles())
+	}
+
+	names := listDir(t, dir)
+	// 每个文件写入两行
+	if len(names) != 10 {
+		t.Fatalf("expected app.log and 9 backups, got %v", names)
+	}
+	for _, name := range names {
+		info, _ := os.Stat(filepath.Join(dir, name))
+		if info.Size() > 100 {
+			t.Errorf("%s exceeds max size: %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.log.9")); err != nil {
+		t.Errorf("expected numbered backups, got %v", names)
+	}
+}
+
+func TestRotateRetentionAndCompress(t *testing.T) {
+	l, dir := newFileLogger(t)
+
+	old := filepath.Join(dir, "app.log.2000010100")
+	if err := ioutil.WriteFile(old, []byte("old\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	_ = os.Chtimes(old, past, past)
+
+	l.SetMaxSize(100)
+	l.SetMaxAge(24 * time.Hour)
+	l.SetMaxBackups(2)
+	l.SetCompress(true)
+
+	for i := 0; i < 10; i++ {
+		l.Print("line", i, strings.Repeat("x", 40))
+		// 保证备份的修改时间不同
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	want := []string{"app.log", "app.log.8.gz", "app.log.9.gz"}
+	waitFor(t, dir, want)
+
+	f, err := os.Open(filepath.Join(dir, "app.log.9.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil || !strings.Contains(string(data), "line 8") {
+		t.Errorf("unexpected compressed content %q: %v", data, err)
+	}
+}
+
+func TestIsBackupName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app.log.1", true},
+		{"app.log.12.gz", true},
+		{"app.log.2020010215", true},
+		{"app.log.20200102", true},
+		{"app.log.2020010215.3", true},
+		{"app.log.20200102.3.gz", true},
+		{"app.log", false},
+		{"app.log.0", false},
+		{"app.log.01", false},
+		{"app.log.gz", false},
+		{"app.log.bak", false},
+		{"app.log.swp", false},
+		{"app.log.1.bak", false},
+		{"app.log.2020-01-02", false},
+		{"app.log.3.2020010215", false},
+		{"app.log.2020010215.1.2", false},
+		{"app.logger.1", false},
+		{"other.log.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBackupName(tt.name, "app.log"); got != tt.want {
+			t.Errorf("isBackupName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMillKeepsUnrelatedFiles(t *testing.T) {
+	l, dir := newFileLogger(t)
+
+	past := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"app.log.bak", "app.log.swp", "app.logger", "app.log.2000010100"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte("old\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, past, past)
+	}
+
+	l.SetMaxSize(100)
+	l.SetMaxAge(24 * time.Hour)
+	l.SetMaxBackups(1)
+	for i := 0; i < 3; i++ {
+		l.Print("line", i, strings.Repeat("x", 60))
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	waitFor(t, dir, []string{"app.log", "app.log.2", "app.log.bak", "app.log.swp", "app.logger"})
+}
+
+func millRunning() bool {
+	buf := make([]byte, 1<<20)
+	return strings.Contains(string(buf[:runtime.Stack(buf, true)]), "(*core).millRun(")
+}
+
+// 等待清理备份的 goroutine 启动或结束
+func waitMill(t *testing.T, running bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for millRunning() != running {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected millRun running=%v", running)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseStopsMill(t *testing.T) {
+	l, dir := newFileLogger(t)
+	l.SetMaxSize(50)
+	l.SetMaxBackups(1)
+
+	for i := 0; i < 3; i++ {
+		l.Print(strings.Repeat("x", 40))
+	}
+	waitFor(t, dir, []string{"app.log", "app.log.2"})
+	waitMill(t, true)
+
+	_ = l.Close()
+	waitMill(t, false)
+
+	// Close 之后继续写入时重新启动
+	for i := 0; i < 3; i++ {
+		l.Print(strings.Repeat("y", 40))
+	}
+	waitMill(t, true)
+	waitFor(t, dir, []string{"app.log", "app.log.4"})
+}
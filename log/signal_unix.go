@@ -0,0 +1,11 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// 切换调试级别的信号
+var debugSignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,10 @@
+//go:build windows
+
+package log
+
+import (
+	"os"
+)
+
+// Windows 不支持 SIGUSR1
+var debugSignal os.Signal
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 按级别范围输出到另一个 Logger
+type sink struct {
+	min, max LogLevel
+	logger   *Logger
+}
+
+// AddSink 将 [min, max] 级别的日志输出到 out，添加后 l 本身不再直接输出
+// 日志由 l 过滤级别和格式化，out 只负责写入，可以单独设置文件、切割和异步写入，如：
+//
+//	info, errs := log.NewLogger(), log.NewLogger()
+//	info.SetOutputByName("info.log")
+//	errs.SetOutputByName("error.log")
+//	logger.AddSink(log.DEBUG, log.WARN, info)
+//	logger.AddSink(log.ERROR, log.FATAL, errs)
+func (l *Logger) AddSink(min, max LogLevel, out *Logger) {
+	l.asyncMu.Lock()
+	defer l.asyncMu.Unlock()
+
+	sinks := append(l.loadSinks(), sink{min: min, max: max, logger: out})
+	l.sinks.Store(sinks)
+}
+
+func (l *Logger) loadSinks() []sink {
+	sinks, _ := l.sinks.Load().([]sink)
+	return sinks
+}
+
+// 写入级别范围匹配的 sink，没有 sink 时返回 false
+func (l *Logger) writeSinks(level LogLevel, text []byte) bool {
+	sinks := l.loadSinks()
+	if len(sinks) == 0 {
+		return false
+	}
+
+	for _, s := range sinks {
+		if level >= s.min && level <= s.max {
+			s.logger.write(level, text)
+		}
+	}
+	return true
+}
+
+func (level LogLevel) String() string {
+	if level >= DEBUG && int(level) < len(levels) {
+		return levels[level]
+	}
+	return "unknown"
+}
+
+// ParseLevel 解析级别名称，不区分大小写，如 "info"、"WARN"
+func ParseLevel(s string) (LogLevel, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "warning" {
+		name = "warn"
+	}
+
+	for i, level := range levels {
+		if level == name {
+			return LogLevel(i), nil
+		}
+	}
+	return DEBUG, fmt.Errorf("log: unknown level '%s'", s)
+}
+
+// "debug-warn" => DEBUG, WARN；"error" => ERROR, ERROR
+func parseLevelRange(s string) (min, max LogLevel, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if min, err = ParseLevel(parts[0]); err != nil {
+		return
+	}
+
+	max = min
+	if len(parts) == 2 {
+		if max, err = ParseLevel(parts[1]); err != nil {
+			return
+		}
+	}
+	if min > max {
+		err = fmt.Errorf("log: invalid level range '%s'", s)
+	}
+	return
+}
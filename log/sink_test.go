@@ -0,0 +1,84 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s       string
+		min     LogLevel
+		max     LogLevel
+		invalid bool
+	}{
+		{"info", INFO, INFO, false},
+		{"Debug-WARNING", DEBUG, WARN, false},
+		{" error - fatal ", ERROR, FATAL, false},
+		{"print", PRINT, PRINT, false},
+		{"warn-debug", 0, 0, true},
+		{"verbose", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		min, max, err := parseLevelRange(tt.s)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.s)
+			}
+			continue
+		}
+		if err != nil || min != tt.min || max != tt.max {
+			t.Errorf("%q: expected %s-%s, got %s-%s %v", tt.s, tt.min, tt.max, min, max, err)
+		}
+	}
+}
+
+func TestSinksByConfig(t *testing.T) {
+	opts := newOptions("log")
+	if len(opts.sinks) != 3 || opts.sinks[2].rotate != RotateDaily || opts.sinks[1].rotate != RotateHourly {
+		t.Fatalf("unexpected sinks %+v", opts.sinks)
+	}
+	if opts.GetMaxSize() != 1<<20 || opts.MaxBackups != 3 {
+		t.Fatalf("unexpected rotation settings %+v", opts.LogConfig)
+	}
+
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts.Dir = filepath.Join(dir, "logs")
+
+	l := NewLoggerWithOptions(opts)
+	l.SetLevel(INFO)
+	l.Debug("debug message")
+	l.Info("info message")
+	l.Warn("warn message")
+	l.Error("error message")
+	l.Printf("GET /users 200")
+	_ = l.Close()
+
+	read := func(name string, rotate string) string {
+		data, err := ioutil.ReadFile(filepath.Join(opts.Dir, name+"."+time.Now().Format(rotate)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	info := read("info.log", SuffixFormatForHour)
+	if strings.Contains(info, "debug message") || !strings.Contains(info, "info message") || !strings.Contains(info, "warn message") || strings.Contains(info, "error message") {
+		t.Errorf("unexpected info.log %q", info)
+	}
+	if errors := read("error.log", SuffixFormatForDay); strings.Count(errors, "\n") != 1 || !strings.Contains(errors, "[error]: error message") {
+		t.Errorf("unexpected error.log %q", errors)
+	}
+	if access := read("access.log", SuffixFormatForHour); strings.Count(access, "\n") != 1 || !strings.Contains(access, "GET /users 200") {
+		t.Errorf("unexpected access.log %q", access)
+	}
+}
@@ -0,0 +1,52 @@
+package protobuf
+
+import "errors"
+
+// MIMEType HTTP 请求和响应中 protobuf 编码的 Content-Type
+const MIMEType = "application/x-protobuf"
+
+var ErrNotMessage = errors.New("protobuf: value does not implement protobuf.Message")
+
+// Message 可以按 protobuf 格式编码的消息，由 protogen 根据 .proto 文件生成
+type Message interface {
+	Reset()
+	EncodeProto(e *Encoder) error
+	DecodeProto(d *Decoder) error
+}
+
+func Marshal(m Message) ([]byte, error) {
+	e := Encoder{}
+	if err := m.EncodeProto(&e); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal 先清空 m 再解析，未知字段被忽略
+func Unmarshal(data []byte, m Message) error {
+	m.Reset()
+	return m.DecodeProto(NewDecoder(data))
+}
+
+// Codec 按 protobuf 格式编码，供 RPC 等按 interface{} 传递消息的场景使用
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "proto"
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(Message)
+	if !ok {
+		return nil, ErrNotMessage
+	}
+	return Marshal(m)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(Message)
+	if !ok {
+		return ErrNotMessage
+	}
+	return Unmarshal(data, m)
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 标量类型的 Go 类型和编解码方式，%s 为值的表达式
+type scalar struct {
+	goType  string
+	wire    string
+	nonZero string
+	write   string
+	read    string
+	conv    string
+}
+
+var scalars = map[string]scalar{
+	"double":   {"float64", "WireFixed64", "%s != 0", "e.WriteDouble(%s)", "%s.ReadDouble()", "%s"},
+	"float":    {"float32", "WireFixed32", "%s != 0", "e.WriteFloat(%s)", "%s.ReadFloat()", "%s"},
+	"int32":    {"int32", "WireVarint", "%s != 0", "e.WriteVarint(uint64(%s))", "%s.ReadVarint()", "int32(%s)"},
+	"int64":    {"int64", "WireVarint", "%s != 0", "e.WriteVarint(uint64(%s))", "%s.ReadVarint()", "int64(%s)"},
+	"uint32":   {"uint32", "WireVarint", "%s != 0", "e.WriteVarint(uint64(%s))", "%s.ReadVarint()", "uint32(%s)"},
+	"uint64":   {"uint64", "WireVarint", "%s != 0", "e.WriteVarint(%s)", "%s.ReadVarint()", "%s"},
+	"sint32":   {"int32", "WireVarint", "%s != 0", "e.WriteVarint(protobuf.EncodeZigzag32(%s))", "%s.ReadVarint()", "protobuf.DecodeZigzag32(%s)"},
+	"sint64":   {"int64", "WireVarint", "%s != 0", "e.WriteVarint(protobuf.EncodeZigzag64(%s))", "%s.ReadVarint()", "protobuf.DecodeZigzag64(%s)"},
+	"fixed32":  {"uint32", "WireFixed32", "%s != 0", "e.WriteFixed32(%s)", "%s.ReadFixed32()", "%s"},
+	"fixed64":  {"uint64", "WireFixed64", "%s != 0", "e.WriteFixed64(%s)", "%s.ReadFixed64()", "%s"},
+	"sfixed32": {"int32", "WireFixed32", "%s != 0", "e.WriteFixed32(uint32(%s))", "%s.ReadFixed32()", "int32(%s)"},
+	"sfixed64": {"int64", "WireFixed64", "%s != 0", "e.WriteFixed64(uint64(%s))", "%s.ReadFixed64()", "int64(%s)"},
+	"bool":     {"bool", "WireVarint", "%s", "e.WriteBool(%s)", "%s.ReadVarint()", "%s != 0"},
+	"string":   {"string", "WireBytes", `%s != ""`, "e.WriteString(%s)", "%s.ReadString()", "%s"},
+	"bytes":    {"[]byte", "WireBytes", "len(%s) > 0", "e.WriteBytes(%s)", "%s.ReadBytes()", "%s"},
+}
+
+type generator struct {
+	file *protoFile
+	buf  bytes.Buffer
+}
+
+func generate(f *protoFile, pkgName string) ([]byte, error) {
+	if pkgName == "" {
+		pkgName = f.goPackageName()
+	}
+
+	g := &generator{file: f}
+	g.p("// Code generated by protogen. DO NOT EDIT.")
+	g.p("// source: ", filepath.Base(f.name))
+	g.p()
+	g.p("package ", pkgName)
+	g.p()
+
+	imports := []string{"lib/protobuf"}
+	if len(f.enums) > 0 {
+		imports = append(imports, "strconv")
+	}
+	if len(f.services) > 0 {
+		imports = append(imports, "context", "lib/server/rpc")
+	}
+	sort.Strings(imports)
+	g.p("import (")
+	for _, imp := range imports {
+		g.p(`"`, imp, `"`)
+	}
+	g.p(")")
+	g.p()
+
+	for _, e := range f.enums {
+		g.genEnum(e)
+	}
+	for _, m := range f.messages {
+		g.genMessage(m)
+	}
+	for _, s := range f.services {
+		g.genService(s)
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: format generated code: %v", f.name, err)
+	}
+	return src, nil
+}
+
+func (g *generator) p(args ...interface{}) {
+	fmt.Fprint(&g.buf, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) genEnum(e *enum) {
+	name := goTypeName(e.fullName)
+
+	// 嵌套枚举的值以外层消息名为前缀，与 protoc-gen-go 一致
+	prefix := name
+	if i := strings.LastIndexByte(e.fullName, '.'); i >= 0 {
+		prefix = goTypeName(e.fullName[:i])
+	}
+
+	g.p("type ", name, " int32")
+	g.p()
+	g.p("const (")
+	for _, v := range e.values {
+		g.p(prefix, "_", v.name, " ", name, " = ", v.number)
+	}
+	g.p(")")
+	g.p()
+	g.p("var ", name, "_name = map[int32]string{")
+	seen := make(map[int]bool)
+	for _, v := range e.values {
+		// allow_alias 时同一个值只保留第一个名称
+		if !seen[v.number] {
+			seen[v.number] = true
+			g.p(v.number, `: "`, v.name, `",`)
+		}
+	}
+	g.p("}")
+	g.p()
+	g.p("func (x ", name, ") String() string {")
+	g.p("if s, ok := ", name, "_name[int32(x)]; ok {")
+	g.p("return s")
+	g.p("}")
+	g.p("return strconv.Itoa(int(x))")
+	g.p("}")
+	g.p()
+}
+
+func (g *generator) genMessage(m *message) {
+	name := goTypeName(m.fullName)
+
+	g.p("type ", name, " struct {")
+	for _, f := range m.fields {
+		g.p(goFieldName(f.name), " ", g.goType(f), " `json:\"", f.name, ",omitempty\"`")
+	}
+	g.p("}")
+	g.p()
+	g.p("func (m *", name, ") Reset() {")
+	g.p("*m = ", name, "{}")
+	g.p("}")
+	g.p()
+
+	g.p("func (m *", name, ") EncodeProto(e *protobuf.Encoder) error {")
+	g.p("if m == nil {")
+	g.p("return nil")
+	g.p("}")
+	for _, f := range m.fields {
+		g.genEncodeField(f)
+	}
+	g.p("return nil")
+	g.p("}")
+	g.p()
+
+	g.p("func (m *", name, ") DecodeProto(d *protobuf.Decoder) error {")
+	g.p("for !d.EOF() {")
+	g.p("field, wt, err := d.ReadTag()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("switch field {")
+	for _, f := range m.fields {
+		g.p("case ", f.number, ":")
+		g.genDecodeField(f)
+	}
+	g.p("default:")
+	g.p("if err := d.Skip(wt); err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("}")
+	g.p("}")
+	g.p("return nil")
+	g.p("}")
+	g.p()
+}
+
+func (g *generator) genEncodeField(f *field) {
+	v := "m." + goFieldName(f.name)
+
+	switch {
+	case f.isMap:
+		g.p("for k, v := range ", v, " {")
+		g.p("if err := e.EncodeEmbedded(", f.number, ", func(e *protobuf.Encoder) error {")
+		g.genEncodeSingle(1, f.keyType, "k")
+		if g.isMessage(f.valueType) {
+			g.p("return e.EncodeMessage(2, v)")
+		} else {
+			g.genEncodeSingle(2, f.valueType, "v")
+			g.p("return nil")
+		}
+		g.p("}); err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("}")
+	case f.repeated && g.isMessage(f.typeName):
+		g.p("for _, v := range ", v, " {")
+		g.p("if err := e.EncodeMessage(", f.number, ", v); err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("}")
+	case f.repeated && g.isPackable(f.typeName):
+		g.p("e.EncodePacked(", f.number, ", len(", v, "), func(e *protobuf.Encoder) {")
+		g.p("for _, v := range ", v, " {")
+		g.p(fmt.Sprintf(g.scalar(f.typeName).write, "v"))
+		g.p("}")
+		g.p("})")
+	case f.repeated:
+		s := g.scalar(f.typeName)
+		g.p("for _, v := range ", v, " {")
+		g.p("e.WriteTag(", f.number, ", protobuf.", s.wire, ")")
+		g.p(fmt.Sprintf(s.write, "v"))
+		g.p("}")
+	case f.optional && !g.isMessage(f.typeName):
+		s := g.scalar(f.typeName)
+		g.p("if ", v, " != nil {")
+		g.p("e.WriteTag(", f.number, ", protobuf.", s.wire, ")")
+		g.p(fmt.Sprintf(s.write, "*"+v))
+		g.p("}")
+	default:
+		g.genEncodeSingle(f.number, f.typeName, v)
+	}
+}
+
+// 单个值，零值不写入
+func (g *generator) genEncodeSingle(number int, typeName, v string) {
+	if g.isMessage(typeName) {
+		g.p("if ", v, " != nil {")
+		g.p("if err := e.EncodeMessage(", number, ", ", v, "); err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("}")
+		return
+	}
+
+	s := g.scalar(typeName)
+	g.p("if ", fmt.Sprintf(s.nonZero, v), " {")
+	g.p("e.WriteTag(", number, ", protobuf.", s.wire, ")")
+	g.p(fmt.Sprintf(s.write, v))
+	g.p("}")
+}
+
+func (g *generator) genDecodeField(f *field) {
+	v := "m." + goFieldName(f.name)
+
+	switch {
+	case f.isMap:
+		g.p("if err := d.Expect(wt, protobuf.WireBytes); err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("entry, err := d.ReadEmbedded()")
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("var key ", g.goScalarType(f.keyType))
+		if g.isMessage(f.valueType) {
+			g.p("value := new(", goTypeName(f.valueType), ")")
+		} else {
+			g.p("var value ", g.goScalarType(f.valueType))
+		}
+		g.p("for !entry.EOF() {")
+		g.p("field, wt, err := entry.ReadTag()")
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("switch field {")
+		g.p("case 1:")
+		g.genDecodeValue("entry", f.keyType, "key = %s")
+		g.p("case 2:")
+		if g.isMessage(f.valueType) {
+			g.genDecodeMessage("entry", "value")
+		} else {
+			g.genDecodeValue("entry", f.valueType, "value = %s")
+		}
+		g.p("default:")
+		g.p("if err := entry.Skip(wt); err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("}")
+		g.p("}")
+		g.p("if ", v, " == nil {")
+		g.p(v, " = make(", g.goType(f), ")")
+		g.p("}")
+		g.p(v, "[key] = value")
+	case g.isMessage(f.typeName) && f.repeated:
+		g.p("item := new(", goTypeName(f.typeName), ")")
+		g.genDecodeMessage("d", "item")
+		g.p(v, " = append(", v, ", item)")
+	case g.isMessage(f.typeName):
+		g.p("if ", v, " == nil {")
+		g.p(v, " = new(", goTypeName(f.typeName), ")")
+		g.p("}")
+		g.genDecodeMessage("d", v)
+	case f.repeated && g.isPackable(f.typeName):
+		s := g.scalar(f.typeName)
+		g.p("if err := d.Packed(wt, protobuf.", s.wire, ", func(d *protobuf.Decoder) error {")
+		g.genReadValue("d", f.typeName, v+" = append("+v+", %s)")
+		g.p("return nil")
+		g.p("}); err != nil {")
+		g.p("return err")
+		g.p("}")
+	case f.repeated:
+		g.genDecodeValue("d", f.typeName, v+" = append("+v+", %s)")
+	case f.optional:
+		g.genDecodeValue("d", f.typeName, "x := %s\n"+v+" = &x")
+	default:
+		g.genDecodeValue("d", f.typeName, v+" = %s")
+	}
+}
+
+func (g *generator) genDecodeMessage(d, v string) {
+	g.p("if err := ", d, ".Expect(wt, protobuf.WireBytes); err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("if err := ", d, ".ReadMessage(", v, "); err != nil {")
+	g.p("return err")
+	g.p("}")
+}
+
+// 校验编码类型后读取一个值，assign 中的 %s 为转换后的值
+func (g *generator) genDecodeValue(d, typeName, assign string) {
+	g.p("if err := ", d, ".Expect(wt, protobuf.", g.scalar(typeName).wire, "); err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.genReadValue(d, typeName, assign)
+}
+
+func (g *generator) genReadValue(d, typeName, assign string) {
+	s := g.scalar(typeName)
+	g.p("v, err := ", fmt.Sprintf(s.read, d))
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p(fmt.Sprintf(assign, fmt.Sprintf(s.conv, "v")))
+}
+
+func (g *generator) genService(s *service) {
+	name := goTypeName(s.name)
+
+	g.p("// ", name, "Server ", s.name, " 服务端接口，通过 Register", name, "Server 注册到 rpc.RpcService")
+	g.p("type ", name, "Server interface {")
+	for _, m := range s.methods {
+		g.p(goFieldName(m.name), "(ctx context.Context, req *", goTypeName(m.inputFull), ", reply *", goTypeName(m.outputFull), ") error")
+	}
+	g.p("}")
+	g.p()
+	g.p("func Register", name, "Server(s *rpc.RpcService, srv ", name, "Server) {")
+	g.p(`s.RegisterName("`, s.name, `", srv)`)
+	g.p("}")
+	g.p()
+	g.p("// ", name, "Client ", s.name, " 客户端，客户端配置中的 codec 可以为 json 或 proto")
+	g.p("type ", name, "Client struct {")
+	g.p("client *rpc.Client")
+	g.p("}")
+	g.p()
+	g.p("func New", name, "Client(client *rpc.Client) *", name, "Client {")
+	g.p("return &", name, "Client{client: client}")
+	g.p("}")
+	g.p()
+	for _, m := range s.methods {
+		output := goTypeName(m.outputFull)
+		g.p("func (c *", name, "Client) ", goFieldName(m.name), "(ctx context.Context, req *", goTypeName(m.inputFull), ") (*", output, ", error) {")
+		g.p("reply := new(", output, ")")
+		g.p(`if err := c.client.Call(ctx, "`, s.name, ".", m.name, `", req, reply); err != nil {`)
+		g.p("return nil, err")
+		g.p("}")
+		g.p("return reply, nil")
+		g.p("}")
+		g.p()
+	}
+}
+
+func (g *generator) isMessage(typeName string) bool {
+	if _, ok := scalars[typeName]; ok {
+		return false
+	}
+	return g.file.enum(typeName) == nil
+}
+
+func (g *generator) isPackable(typeName string) bool {
+	if g.isMessage(typeName) {
+		return false
+	}
+	return typeName != "string" && typeName != "bytes"
+}
+
+// 标量或枚举的编解码方式
+func (g *generator) scalar(typeName string) scalar {
+	if s, ok := scalars[typeName]; ok {
+		return s
+	}
+
+	name := goTypeName(typeName)
+	return scalar{
+		goType:  name,
+		wire:    "WireVarint",
+		nonZero: "%s != 0",
+		write:   "e.WriteVarint(uint64(%s))",
+		read:    "%s.ReadVarint()",
+		conv:    name + "(%s)",
+	}
+}
+
+func (g *generator) goScalarType(typeName string) string {
+	return g.scalar(typeName).goType
+}
+
+func (g *generator) goType(f *field) string {
+	if f.isMap {
+		value := g.goScalarType(f.valueType)
+		if g.isMessage(f.valueType) {
+			value = "*" + goTypeName(f.valueType)
+		}
+		return "map[" + g.goScalarType(f.keyType) + "]" + value
+	}
+
+	t := g.goScalarType(f.typeName)
+	if g.isMessage(f.typeName) {
+		t = "*" + goTypeName(f.typeName)
+	}
+
+	switch {
+	case f.repeated:
+		return "[]" + t
+	case f.optional && !g.isMessage(f.typeName):
+		return "*" + t
+	}
+	return t
+}
+
+// go_package 的最后一段，如 "lib/proto/hello;hello" 或 "lib/proto/hello"
+// 未设置时使用 proto 包名
+func (f *protoFile) goPackageName() string {
+	if f.goPackage != "" {
+		pkg := f.goPackage
+		if i := strings.LastIndexByte(pkg, ';'); i >= 0 {
+			return pkg[i+1:]
+		}
+		return filepath.Base(pkg)
+	}
+	if f.pkg != "" {
+		return strings.ReplaceAll(f.pkg, ".", "_")
+	}
+	return strings.TrimSuffix(filepath.Base(f.name), ".proto")
+}
+
+// "Outer.Inner" => "Outer_Inner"
+func goTypeName(fullName string) string {
+	parts := strings.Split(fullName, ".")
+	for i, part := range parts {
+		parts[i] = camelCase(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+// "user_id" => "UserId"
+func goFieldName(name string) string {
+	return camelCase(name)
+}
+
+func camelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i+1 < len(s) && s[i+1] >= 'a' && s[i+1] <= 'z':
+			upper = true
+		case upper && c >= 'a' && c <= 'z':
+			b.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteByte(c)
+			upper = false
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+// protogen 根据 .proto 文件生成消息的 protobuf 编解码代码，以及 RPC 服务端接口和客户端
+//
+//	protogen [-out dir] [-package name] hello.proto ...
+//
+// hello.proto 生成 hello.pb.go，默认输出到 .proto 文件所在目录
+// 包名依次取 -package、option go_package、package 声明
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	out := flag.String("out", "", "output directory, defaults to the directory of each .proto file")
+	pkg := flag.String("package", "", "go package name of the generated code")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: protogen [-out dir] [-package name] file.proto ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, file := range flag.Args() {
+		if err := generateFile(file, *out, *pkg); err != nil {
+			fmt.Fprintln(os.Stderr, "protogen:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func generateFile(file, out, pkg string) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	f, err := parseProto(file, src)
+	if err != nil {
+		return err
+	}
+
+	code, err := generate(f, pkg)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		out = filepath.Dir(file)
+	}
+	name := strings.TrimSuffix(filepath.Base(file), ".proto") + ".pb.go"
+
+	return ioutil.WriteFile(filepath.Join(out, name), code, 0644)
+}
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 解析后的 .proto 文件，只支持 proto3 中的常用部分：
+// message（含嵌套）、enum、map、repeated、optional 和不含流式调用的 service
+type protoFile struct {
+	name      string
+	pkg       string
+	goPackage string
+	messages  []*message
+	enums     []*enum
+	services  []*service
+}
+
+type message struct {
+	// 去掉包名后的完整名称，如 "Outer.Inner"
+	fullName string
+	fields   []*field
+}
+
+type field struct {
+	name     string
+	number   int
+	typeName string
+	repeated bool
+	optional bool
+
+	// map 字段
+	isMap     bool
+	keyType   string
+	valueType string
+}
+
+type enum struct {
+	fullName string
+	values   []*enumValue
+}
+
+type enumValue struct {
+	name   string
+	number int
+}
+
+type service struct {
+	name    string
+	methods []*method
+}
+
+type method struct {
+	name       string
+	input      string
+	output     string
+	inputFull  string
+	outputFull string
+}
+
+type token struct {
+	text string
+	str  bool
+	line int
+}
+
+type parser struct {
+	file   string
+	tokens []token
+	pos    int
+	result *protoFile
+}
+
+func parseProto(name string, src []byte) (f *protoFile, err error) {
+	tokens, err := tokenize(name, string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{file: name, tokens: tokens, result: &protoFile{name: name}}
+	defer func() {
+		if r := recover(); r != nil {
+			if pe, ok := r.(parseError); ok {
+				f, err = nil, pe
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	p.parseFile()
+	if err := p.result.resolve(); err != nil {
+		return nil, err
+	}
+
+	return p.result, nil
+}
+
+type parseError string
+
+func (e parseError) Error() string {
+	return string(e)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	panic(parseError(fmt.Sprintf("%s:%d: %s", p.file, line, fmt.Sprintf(format, args...))))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if p.eof() {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *parser) next() token {
+	if p.eof() {
+		p.errorf("unexpected end of file")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) expect(text string) {
+	if t := p.next(); t.str || t.text != text {
+		p.pos--
+		p.errorf("expected %q, found %q", text, t.text)
+	}
+}
+
+func (p *parser) ident() string {
+	t := p.next()
+	if t.str || !isIdent(t.text) {
+		p.pos--
+		p.errorf("expected identifier, found %q", t.text)
+	}
+	return t.text
+}
+
+func (p *parser) str() string {
+	t := p.next()
+	if !t.str {
+		p.pos--
+		p.errorf("expected string, found %q", t.text)
+	}
+	return t.text
+}
+
+func (p *parser) number() int {
+	t := p.next()
+	text := t.text
+	if text == "-" {
+		text += p.next().text
+	}
+	n, err := strconv.ParseInt(text, 0, 32)
+	if t.str || err != nil {
+		p.pos--
+		p.errorf("expected number, found %q", text)
+	}
+	return int(n)
+}
+
+func (p *parser) parseFile() {
+	for !p.eof() {
+		switch p.ident() {
+		case "syntax":
+			p.expect("=")
+			if syntax := p.str(); syntax != "proto3" {
+				p.errorf("only proto3 is supported, found %q", syntax)
+			}
+			p.expect(";")
+		case "package":
+			p.result.pkg = p.ident()
+			p.expect(";")
+		case "import":
+			p.errorf("imports are not supported")
+		case "option":
+			name, value := p.parseOption()
+			if name == "go_package" {
+				p.result.goPackage = value
+			}
+		case "message":
+			p.parseMessage("")
+		case "enum":
+			p.parseEnum("")
+		case "service":
+			p.parseService()
+		default:
+			p.pos--
+			p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+// option name = value; 只返回字符串或标识符类型的值
+func (p *parser) parseOption() (string, string) {
+	var name string
+	for p.peek() != "=" {
+		name += p.next().text
+	}
+	p.expect("=")
+
+	value := ""
+	if p.peek() == "{" {
+		p.skipBlock()
+	} else {
+		value = p.next().text
+	}
+	p.expect(";")
+
+	return name, value
+}
+
+// 跳过 {...}，支持嵌套
+func (p *parser) skipBlock() {
+	p.expect("{")
+	for depth := 1; depth > 0; {
+		switch t := p.next(); {
+		case t.str:
+		case t.text == "{":
+			depth++
+		case t.text == "}":
+			depth--
+		}
+	}
+}
+
+// 跳过字段选项 [packed = false, deprecated = true]
+func (p *parser) skipFieldOptions() {
+	if p.peek() != "[" {
+		return
+	}
+	for p.next().text != "]" {
+	}
+}
+
+// reserved 2, 15, 9 to 11; 或 reserved "foo", "bar";
+func (p *parser) skipStatement() {
+	for p.next().text != ";" {
+	}
+}
+
+func (p *parser) parseMessage(parent string) {
+	m := &message{fullName: qualify(parent, p.ident())}
+	p.result.messages = append(p.result.messages, m)
+
+	p.expect("{")
+	for p.peek() != "}" {
+		switch p.peek() {
+		case ";":
+			p.next()
+		case "message":
+			p.next()
+			p.parseMessage(m.fullName)
+		case "enum":
+			p.next()
+			p.parseEnum(m.fullName)
+		case "option":
+			p.next()
+			p.parseOption()
+		case "reserved":
+			p.skipStatement()
+		case "oneof", "extensions", "extend", "group":
+			p.errorf("%s is not supported", p.peek())
+		default:
+			m.fields = append(m.fields, p.parseField())
+		}
+	}
+	p.expect("}")
+
+	seen := make(map[int]bool)
+	for _, f := range m.fields {
+		if seen[f.number] {
+			p.errorf("duplicate field number %d in message %s", f.number, m.fullName)
+		}
+		seen[f.number] = true
+	}
+}
+
+func (p *parser) parseField() *field {
+	f := new(field)
+
+	switch p.peek() {
+	case "repeated":
+		p.next()
+		f.repeated = true
+	case "optional":
+		p.next()
+		f.optional = true
+	case "required":
+		p.errorf("required is not supported in proto3")
+	}
+
+	if p.peek() == "map" {
+		if f.repeated || f.optional {
+			p.errorf("map fields cannot be repeated or optional")
+		}
+		p.next()
+		f.isMap = true
+		p.expect("<")
+		f.keyType = p.ident()
+		p.expect(",")
+		f.valueType = p.ident()
+		p.expect(">")
+
+		switch f.keyType {
+		case "double", "float", "bytes":
+			p.errorf("invalid map key type %s", f.keyType)
+		}
+		if _, ok := scalars[f.keyType]; !ok {
+			p.errorf("invalid map key type %s", f.keyType)
+		}
+	} else {
+		f.typeName = p.ident()
+	}
+
+	f.name = p.ident()
+	p.expect("=")
+	f.number = p.number()
+	if f.number < 1 || f.number > 1<<29-1 || (f.number >= 19000 && f.number <= 19999) {
+		p.errorf("invalid field number %d", f.number)
+	}
+	p.skipFieldOptions()
+	p.expect(";")
+
+	return f
+}
+
+func (p *parser) parseEnum(parent string) {
+	e := &enum{fullName: qualify(parent, p.ident())}
+	p.result.enums = append(p.result.enums, e)
+
+	p.expect("{")
+	for p.peek() != "}" {
+		switch p.peek() {
+		case ";":
+			p.next()
+		case "option":
+			p.next()
+			p.parseOption()
+		case "reserved":
+			p.skipStatement()
+		default:
+			v := &enumValue{name: p.ident()}
+			p.expect("=")
+			v.number = p.number()
+			p.skipFieldOptions()
+			p.expect(";")
+			e.values = append(e.values, v)
+		}
+	}
+	p.expect("}")
+
+	if len(e.values) == 0 || e.values[0].number != 0 {
+		p.errorf("the first value of enum %s must be zero", e.fullName)
+	}
+}
+
+func (p *parser) parseService() {
+	s := &service{name: p.ident()}
+	p.result.services = append(p.result.services, s)
+
+	p.expect("{")
+	for p.peek() != "}" {
+		switch p.ident() {
+		case "option":
+			p.parseOption()
+		case "rpc":
+			m := &method{name: p.ident()}
+			p.expect("(")
+			if p.peek() == "stream" {
+				p.errorf("streaming rpc is not supported")
+			}
+			m.input = p.ident()
+			p.expect(")")
+			p.expect("returns")
+			p.expect("(")
+			if p.peek() == "stream" {
+				p.errorf("streaming rpc is not supported")
+			}
+			m.output = p.ident()
+			p.expect(")")
+			if p.peek() == "{" {
+				p.skipBlock()
+			} else {
+				p.expect(";")
+			}
+			s.methods = append(s.methods, m)
+		default:
+			p.pos--
+			p.errorf("unexpected %q", p.peek())
+		}
+	}
+	p.expect("}")
+}
+
+// 将字段和方法中引用的类型解析为完整名称
+func (f *protoFile) resolve() error {
+	types := make(map[string]bool)
+	for _, m := range f.messages {
+		types[m.fullName] = true
+	}
+	for _, e := range f.enums {
+		types[e.fullName] = true
+	}
+
+	for _, m := range f.messages {
+		for _, fd := range m.fields {
+			name := &fd.typeName
+			if fd.isMap {
+				name = &fd.valueType
+			}
+			if _, ok := scalars[*name]; ok {
+				continue
+			}
+
+			full, ok := f.lookup(types, m.fullName, *name)
+			if !ok {
+				return fmt.Errorf("%s: unknown type %s in message %s", f.name, *name, m.fullName)
+			}
+			*name = full
+		}
+	}
+
+	for _, s := range f.services {
+		for _, m := range s.methods {
+			var ok bool
+			if m.inputFull, ok = f.lookup(types, "", m.input); !ok || f.enum(m.inputFull) != nil {
+				return fmt.Errorf("%s: unknown message %s in service %s", f.name, m.input, s.name)
+			}
+			if m.outputFull, ok = f.lookup(types, "", m.output); !ok || f.enum(m.outputFull) != nil {
+				return fmt.Errorf("%s: unknown message %s in service %s", f.name, m.output, s.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// 按 protobuf 的作用域规则，从内层向外层查找类型
+func (f *protoFile) lookup(types map[string]bool, scope, name string) (string, bool) {
+	if strings.HasPrefix(name, ".") {
+		name = name[1:]
+		if f.pkg != "" {
+			if !strings.HasPrefix(name, f.pkg+".") {
+				return "", false
+			}
+			name = name[len(f.pkg)+1:]
+		}
+		return name, types[name]
+	}
+
+	for {
+		if full := qualify(scope, name); types[full] {
+			return full, true
+		}
+		if scope == "" {
+			break
+		}
+		if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+
+	if f.pkg != "" && strings.HasPrefix(name, f.pkg+".") {
+		name = name[len(f.pkg)+1:]
+		return name, types[name]
+	}
+	return "", false
+}
+
+func (f *protoFile) enum(fullName string) *enum {
+	for _, e := range f.enums {
+		if e.fullName == fullName {
+			return e
+		}
+	}
+	return nil
+}
+
+func qualify(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || r == '.' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func tokenize(name, src string) ([]token, error) {
+	var tokens []token
+	line := 1
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated comment", name, line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, fmt.Errorf("%s:%d: unterminated string", name, line)
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("%s:%d: unterminated string", name, line)
+			}
+			body := src[i+1 : j]
+			if c == '\'' {
+				body = strings.ReplaceAll(body, `"`, `\"`)
+			}
+			s, err := strconv.Unquote(`"` + body + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid string %s", name, line, src[i:j+1])
+			}
+			tokens = append(tokens, token{text: s, str: true, line: line})
+			i = j + 1
+		case c == '_' || c == '.' || isAlnum(c):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || isAlnum(src[j])) {
+				j++
+			}
+			tokens = append(tokens, token{text: src[i:j], line: line})
+			i = j
+		default:
+			tokens = append(tokens, token{text: string(c), line: line})
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
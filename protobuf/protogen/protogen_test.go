@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const sampleProto = "testdata/sample.proto"
+
+func parseSample(t *testing.T) *protoFile {
+	t.Helper()
+
+	src, err := ioutil.ReadFile(sampleProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseProto(sampleProto, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	f := parseSample(t)
+
+	if f.pkg != "sample" || f.goPackage != "lib/protobuf/protogen/testdata;sample" {
+		t.Errorf("package = %q, go_package = %q", f.pkg, f.goPackage)
+	}
+	if name := f.goPackageName(); name != "sample" {
+		t.Errorf("goPackageName = %q", name)
+	}
+
+	var messages, enums []string
+	for _, m := range f.messages {
+		messages = append(messages, m.fullName)
+	}
+	for _, e := range f.enums {
+		enums = append(enums, e.fullName)
+	}
+	wantMessages := []string{"User", "User.Address", "GetUserRequest", "ListUsersRequest", "ListUsersReply"}
+	if !reflect.DeepEqual(messages, wantMessages) {
+		t.Errorf("messages = %v, want %v", messages, wantMessages)
+	}
+	if want := []string{"Status", "User.Role"}; !reflect.DeepEqual(enums, want) {
+		t.Errorf("enums = %v, want %v", enums, want)
+	}
+
+	role := f.enum("User.Role")
+	if len(role.values) != 4 || role.values[3].name != "ROOT" || role.values[3].number != 2 {
+		t.Errorf("User.Role values = %+v", role.values)
+	}
+
+	// 类型引用解析为去掉包名的完整名称
+	fields := make(map[string]*field)
+	for _, fd := range f.messages[0].fields {
+		fields[fd.name] = fd
+	}
+	tests := []struct {
+		name string
+		want field
+	}{
+		{"id", field{name: "id", number: 1, typeName: "int64"}},
+		{"role", field{name: "role", number: 4, typeName: "User.Role"}},
+		{"address", field{name: "address", number: 5, typeName: "User.Address"}},
+		{"history", field{name: "history", number: 6, typeName: "User.Address", repeated: true}},
+		{"deltas", field{name: "deltas", number: 8, typeName: "sint64", repeated: true}},
+		{"addresses", field{name: "addresses", number: 17, isMap: true, keyType: "int64", valueType: "User.Address"}},
+		{"states", field{name: "states", number: 18, isMap: true, keyType: "uint32", valueType: "Status"}},
+		{"age", field{name: "age", number: 26, typeName: "int32", optional: true}},
+	}
+	for _, tt := range tests {
+		if got := fields[tt.name]; got == nil || *got != tt.want {
+			t.Errorf("field %s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if got := f.messages[3].fields[1].typeName; got != "Status" {
+		t.Errorf("fully qualified .sample.Status resolved to %q", got)
+	}
+	if got := f.messages[4].fields[1].valueType; got != "User.Address" {
+		t.Errorf("map value User.Address resolved to %q", got)
+	}
+
+	s := f.services[0]
+	if s.name != "UserService" || len(s.methods) != 2 {
+		t.Fatalf("service = %+v", s)
+	}
+	if m := s.methods[1]; m.name != "ListUsers" || m.inputFull != "ListUsersRequest" || m.outputFull != "ListUsersReply" {
+		t.Errorf("method = %+v", m)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"proto2", `syntax = "proto2";`, "only proto3"},
+		{"import", `syntax = "proto3"; import "other.proto";`, "imports are not supported"},
+		{"oneof", `message M { oneof x { int32 a = 1; } }`, "oneof is not supported"},
+		{"required", `message M { required int32 a = 1; }`, "required is not supported"},
+		{"duplicate number", `message M { int32 a = 1; string b = 1; }`, "duplicate field number 1"},
+		{"reserved number", `message M { int32 a = 19000; }`, "invalid field number 19000"},
+		{"float map key", `message M { map<float, int32> m = 1; }`, "invalid map key type float"},
+		{"repeated map", `message M { repeated map<string, int32> m = 1; }`, "cannot be repeated"},
+		{"unknown type", `message M { Missing m = 1; }`, "unknown type Missing"},
+		{"enum zero", `enum E { A = 1; }`, "must be zero"},
+		{"stream", `message M {} service S { rpc Get(stream M) returns (M); }`, "streaming rpc"},
+		{"enum input", `enum E { A = 0; } message M {} service S { rpc Get(E) returns (M); }`, "unknown message E"},
+		{"unterminated", `message M { string s = 1 [default = "x]; }`, "unterminated string"},
+		{"eof", `message M { int32 a = 1;`, "unexpected end of file"},
+		{"line number", "syntax = \"proto3\";\n\nmessage M {\n  int32 = 1;\n}", "test.proto:4:"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseProto("test.proto", []byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// go test -run TestGenerateGolden -update 更新 testdata 中的 .golden 文件
+func TestGenerateGolden(t *testing.T) {
+	code, err := generate(parseSample(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "sample.pb.go.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, want) {
+		t.Errorf("generated code differs from %s, run with -update if the change is intended\n%s", golden, diffLine(code, want))
+	}
+}
+
+// 第一处不同的行
+func diffLine(got, want []byte) string {
+	a, b := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return fmt.Sprintf("line %d:\n got: %s\nwant: %s", i+1, x, y)
+		}
+	}
+	return ""
+}
+
+// 对生成的代码做类型检查，确认可以与 lib/protobuf 和 lib/server/rpc 一起编译
+func TestGeneratedCompiles(t *testing.T) {
+	f := parseSample(t)
+	code, err := generate(f, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 生成的消息类型实现了 protobuf.Message
+	check := "package sample\n\nimport \"lib/protobuf\"\n\nvar (\n"
+	for _, m := range f.messages {
+		check += "\t_ protobuf.Message = (*" + goTypeName(m.fullName) + ")(nil)\n"
+	}
+	check += "\t_ = RegisterUserServiceServer\n\t_ = NewUserServiceClient\n)\n"
+
+	fset := gotoken.NewFileSet()
+	var files []*ast.File
+	for name, src := range map[string]string{"sample.pb.go": string(code), "check.go": check} {
+		file, err := goparser.ParseFile(fset, filepath.Join("testdata", name), src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("sample", fset, files, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,300 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// WireType 字段的编码类型
+type WireType int
+
+const (
+	WireVarint  WireType = 0
+	WireFixed64 WireType = 1
+	WireBytes   WireType = 2
+	WireFixed32 WireType = 5
+)
+
+var (
+	ErrTruncated  = errors.New("protobuf: unexpected end of data")
+	ErrOverflow   = errors.New("protobuf: varint overflows 64 bits")
+	ErrInvalidTag = errors.New("protobuf: invalid field tag")
+	ErrWireType   = errors.New("protobuf: unexpected wire type")
+)
+
+// Encoder 按 protobuf 编码格式追加写入
+// Write 系列方法只写入值，字段标签由调用方通过 WriteTag 写入
+type Encoder struct {
+	buf []byte
+}
+
+func NewEncoder(buf []byte) *Encoder {
+	return &Encoder{buf: buf[:0]}
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+}
+
+func (e *Encoder) WriteTag(field int, wt WireType) {
+	e.WriteVarint(uint64(field)<<3 | uint64(wt))
+}
+
+func (e *Encoder) WriteVarint(v uint64) {
+	for v >= 0x80 {
+		e.buf = append(e.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *Encoder) WriteBool(v bool) {
+	if v {
+		e.WriteVarint(1)
+	} else {
+		e.WriteVarint(0)
+	}
+}
+
+func (e *Encoder) WriteFixed32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) WriteFixed64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) WriteFloat(v float32) {
+	e.WriteFixed32(math.Float32bits(v))
+}
+
+func (e *Encoder) WriteDouble(v float64) {
+	e.WriteFixed64(math.Float64bits(v))
+}
+
+func (e *Encoder) WriteBytes(v []byte) {
+	e.WriteVarint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *Encoder) WriteString(v string) {
+	e.WriteVarint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// EncodeEmbedded 写入长度前缀的嵌套内容，用于消息、map 条目和 packed 数组
+func (e *Encoder) EncodeEmbedded(field int, fn func(e *Encoder) error) error {
+	sub := Encoder{}
+	if err := fn(&sub); err != nil {
+		return err
+	}
+
+	e.WriteTag(field, WireBytes)
+	e.WriteBytes(sub.buf)
+	return nil
+}
+
+// EncodeMessage 写入嵌套消息
+func (e *Encoder) EncodeMessage(field int, m Message) error {
+	return e.EncodeEmbedded(field, m.EncodeProto)
+}
+
+// EncodePacked 以 packed 格式写入 n 个数值，n 为 0 时不写入
+func (e *Encoder) EncodePacked(field int, n int, fn func(e *Encoder)) {
+	if n == 0 {
+		return
+	}
+	_ = e.EncodeEmbedded(field, func(e *Encoder) error {
+		fn(e)
+		return nil
+	})
+}
+
+// Decoder 按 protobuf 编码格式顺序读取
+type Decoder struct {
+	buf []byte
+	pos int
+}
+
+func NewDecoder(buf []byte) *Decoder {
+	return &Decoder{buf: buf}
+}
+
+// EOF 数据是否已经读完
+func (d *Decoder) EOF() bool {
+	return d.pos >= len(d.buf)
+}
+
+func (d *Decoder) ReadTag() (int, WireType, error) {
+	v, err := d.ReadVarint()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	field := v >> 3
+	if field == 0 || field > math.MaxInt32 {
+		return 0, 0, ErrInvalidTag
+	}
+	return int(field), WireType(v & 7), nil
+}
+
+func (d *Decoder) ReadVarint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf[d.pos:])
+	if n == 0 {
+		return 0, ErrTruncated
+	}
+	if n < 0 {
+		return 0, ErrOverflow
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *Decoder) ReadFixed32() (uint32, error) {
+	if len(d.buf)-d.pos < 4 {
+		return 0, ErrTruncated
+	}
+	v := binary.LittleEndian.Uint32(d.buf[d.pos:])
+	d.pos += 4
+	return v, nil
+}
+
+func (d *Decoder) ReadFixed64() (uint64, error) {
+	if len(d.buf)-d.pos < 8 {
+		return 0, ErrTruncated
+	}
+	v := binary.LittleEndian.Uint64(d.buf[d.pos:])
+	d.pos += 8
+	return v, nil
+}
+
+func (d *Decoder) ReadFloat() (float32, error) {
+	v, err := d.ReadFixed32()
+	return math.Float32frombits(v), err
+}
+
+func (d *Decoder) ReadDouble() (float64, error) {
+	v, err := d.ReadFixed64()
+	return math.Float64frombits(v), err
+}
+
+// 返回的切片引用原始数据
+func (d *Decoder) readRaw() ([]byte, error) {
+	n, err := d.ReadVarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, ErrTruncated
+	}
+
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// ReadBytes 返回数据的副本
+func (d *Decoder) ReadBytes() ([]byte, error) {
+	b, err := d.readRaw()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+func (d *Decoder) ReadString() (string, error) {
+	b, err := d.readRaw()
+	return string(b), err
+}
+
+// ReadEmbedded 读取长度前缀的嵌套内容，返回只读取该内容的 Decoder
+func (d *Decoder) ReadEmbedded() (*Decoder, error) {
+	b, err := d.readRaw()
+	if err != nil {
+		return nil, err
+	}
+	return NewDecoder(b), nil
+}
+
+// ReadMessage 读取嵌套消息并合并到 m
+func (d *Decoder) ReadMessage(m Message) error {
+	sub, err := d.ReadEmbedded()
+	if err != nil {
+		return err
+	}
+	return m.DecodeProto(sub)
+}
+
+// Expect 校验字段的编码类型
+func (d *Decoder) Expect(wt, want WireType) error {
+	if wt != want {
+		return ErrWireType
+	}
+	return nil
+}
+
+// Packed 读取数值类型的 repeated 字段，兼容 packed 和非 packed 两种格式
+// fn 每次读取一个元素
+func (d *Decoder) Packed(wt, elem WireType, fn func(d *Decoder) error) error {
+	if wt == elem {
+		return fn(d)
+	}
+	if wt != WireBytes {
+		return ErrWireType
+	}
+
+	sub, err := d.ReadEmbedded()
+	if err != nil {
+		return err
+	}
+	for !sub.EOF() {
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Skip 跳过未知字段
+func (d *Decoder) Skip(wt WireType) error {
+	var err error
+	switch wt {
+	case WireVarint:
+		_, err = d.ReadVarint()
+	case WireFixed64:
+		_, err = d.ReadFixed64()
+	case WireBytes:
+		_, err = d.readRaw()
+	case WireFixed32:
+		_, err = d.ReadFixed32()
+	default:
+		err = ErrWireType
+	}
+	return err
+}
+
+// sint32、sint64 使用的 zigzag 编码
+func EncodeZigzag32(v int32) uint64 {
+	return uint64(uint32(v<<1) ^ uint32(v>>31))
+}
+
+func DecodeZigzag32(v uint64) int32 {
+	return int32(uint32(v)>>1) ^ -int32(v&1)
+}
+
+func EncodeZigzag64(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func DecodeZigzag64(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
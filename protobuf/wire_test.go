@@ -0,0 +1,143 @@
+package protobuf
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// 编码结果取自 protobuf 官方文档的示例
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(e *Encoder)
+		want  []byte
+	}{
+		{"varint 150", func(e *Encoder) { e.WriteTag(1, WireVarint); e.WriteVarint(150) }, []byte{0x08, 0x96, 0x01}},
+		{"string testing", func(e *Encoder) { e.WriteTag(2, WireBytes); e.WriteString("testing") }, []byte{0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}},
+		{"negative int32", func(e *Encoder) { v := int32(-2); e.WriteVarint(uint64(v)) }, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"sint32 -2", func(e *Encoder) { e.WriteVarint(EncodeZigzag32(-2)) }, []byte{0x03}},
+		{"fixed32", func(e *Encoder) { e.WriteFixed32(1) }, []byte{0x01, 0x00, 0x00, 0x00}},
+		{"packed", func(e *Encoder) {
+			e.EncodePacked(4, 3, func(e *Encoder) {
+				for _, v := range []uint64{3, 270, 86942} {
+					e.WriteVarint(v)
+				}
+			})
+		}, []byte{0x22, 0x06, 0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05}},
+		{"empty packed", func(e *Encoder) { e.EncodePacked(4, 0, func(e *Encoder) {}) }, nil},
+	}
+
+	for _, tt := range tests {
+		e := NewEncoder(nil)
+		tt.write(e)
+		if !bytes.Equal(e.Bytes(), tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.name, e.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	e := NewEncoder(nil)
+	e.WriteTag(1, WireVarint)
+	e.WriteVarint(150)
+	e.WriteTag(2, WireFixed64)
+	e.WriteDouble(math.Pi)
+	e.WriteTag(3, WireBytes)
+	e.WriteBytes([]byte("skip me"))
+	e.WriteTag(4, WireFixed32)
+	e.WriteFloat(1.5)
+	e.WriteTag(5, WireVarint) // 非 packed 格式的 repeated 字段
+	e.WriteVarint(7)
+
+	d := NewDecoder(e.Bytes())
+	var got []interface{}
+	for !d.EOF() {
+		field, wt, err := d.ReadTag()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch field {
+		case 1:
+			v, err := d.ReadVarint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		case 2:
+			v, err := d.ReadDouble()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		case 4:
+			v, err := d.ReadFloat()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		case 5:
+			err := d.Packed(wt, WireVarint, func(d *Decoder) error {
+				v, err := d.ReadVarint()
+				got = append(got, v)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		default:
+			if err := d.Skip(wt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	want := []interface{}{uint64(150), math.Pi, float32(1.5), uint64(7)}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(d *Decoder) error
+		want error
+	}{
+		{"truncated varint", []byte{0x96}, func(d *Decoder) error { _, err := d.ReadVarint(); return err }, ErrTruncated},
+		{"overflow varint", bytes.Repeat([]byte{0xff}, 11), func(d *Decoder) error { _, err := d.ReadVarint(); return err }, ErrOverflow},
+		{"zero field", []byte{0x00}, func(d *Decoder) error { _, _, err := d.ReadTag(); return err }, ErrInvalidTag},
+		{"length beyond data", []byte{0x05, 'a'}, func(d *Decoder) error { _, err := d.ReadBytes(); return err }, ErrTruncated},
+		{"truncated fixed64", []byte{1, 2, 3}, func(d *Decoder) error { _, err := d.ReadFixed64(); return err }, ErrTruncated},
+		{"group wire type", nil, func(d *Decoder) error { return d.Skip(3) }, ErrWireType},
+		{"packed wire type", nil, func(d *Decoder) error { return d.Packed(WireFixed32, WireVarint, nil) }, ErrWireType},
+	}
+
+	for _, tt := range tests {
+		if err := tt.read(NewDecoder(tt.data)); err != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	for _, v := range []int32{0, -1, 1, -2, math.MaxInt32, math.MinInt32} {
+		if got := DecodeZigzag32(EncodeZigzag32(v)); got != v {
+			t.Errorf("zigzag32 %d: got %d", v, got)
+		}
+	}
+	for _, v := range []int64{0, -1, 1, math.MaxInt64, math.MinInt64} {
+		if got := DecodeZigzag64(EncodeZigzag64(v)); got != v {
+			t.Errorf("zigzag64 %d: got %d", v, got)
+		}
+	}
+	if EncodeZigzag32(-1) != 1 || EncodeZigzag64(math.MinInt64) != math.MaxUint64 {
+		t.Error("unexpected zigzag encoding")
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"lib/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认访问日志格式
+const DefaultAccessLogFormat = "$time_local $method $status $uri $remote_addr $request_time"
+
+// 访问日志格式为 json 时默认输出的字段
+const DefaultAccessLogFields = "time_local,remote_addr,method,uri,status,body_bytes_sent,request_time,http_referer,http_user_agent,request_id"
+
+// 访问日志格式化
+// 文本格式类似 nginx 的 log_format，以 "$name" 引用变量，如：
+//
+//	$remote_addr - [$time_local] "$method $uri $protocol" $status $body_bytes_sent "$http_referer" "$http_user_agent"
+//
+// 格式为 "json" 时按 fields 输出 JSON 对象
+//
+// 支持的变量：
+//
+//	time_local, remote_addr, method, uri, path, query, protocol, host,
+//	status, body_bytes_sent, request_time(秒), request_time_ms,
+//	http_referer, http_user_agent, request_id, http_<header>(如 http_x_token)
+type accessLogFormatter struct {
+	json     bool
+	fields   []string
+	segments []accessLogSegment
+}
+
+type accessLogSegment struct {
+	text     string
+	variable bool
+}
+
+func newAccessLogFormatter(format string, fields string) *accessLogFormatter {
+	f := new(accessLogFormatter)
+
+	if strings.EqualFold(strings.TrimSpace(format), "json") {
+		f.json = true
+		if fields == "" {
+			fields = DefaultAccessLogFields
+		}
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimPrefix(strings.TrimSpace(field), "$"); field != "" {
+				f.fields = append(f.fields, field)
+			}
+		}
+		return f
+	}
+
+	if format == "" {
+		format = DefaultAccessLogFormat
+	}
+	f.segments = parseAccessLogFormat(format)
+
+	return f
+}
+
+func parseAccessLogFormat(format string) []accessLogSegment {
+	var segments []accessLogSegment
+
+	for len(format) > 0 {
+		i := strings.IndexByte(format, '$')
+		if i < 0 {
+			segments = append(segments, accessLogSegment{text: format})
+			break
+		}
+		if i > 0 {
+			segments = append(segments, accessLogSegment{text: format[:i]})
+		}
+
+		format = format[i+1:]
+		n := 0
+		for n < len(format) && isVariableChar(format[n]) {
+			n++
+		}
+		if n == 0 {
+			segments = append(segments, accessLogSegment{text: "$"})
+			continue
+		}
+
+		segments = append(segments, accessLogSegment{text: format[:n], variable: true})
+		format = format[n:]
+	}
+
+	return segments
+}
+
+func isVariableChar(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func (f *accessLogFormatter) Format(c *Context, latency time.Duration) []byte {
+	if f.json {
+		entry := make(map[string]interface{}, len(f.fields))
+		for _, field := range f.fields {
+			entry[field] = accessLogValue(c, field, latency)
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return nil
+		}
+		return append(b, '\n')
+	}
+
+	var buf bytes.Buffer
+	for _, seg := range f.segments {
+		if !seg.variable {
+			buf.WriteString(seg.text)
+			continue
+		}
+
+		switch v := accessLogValue(c, seg.text, latency).(type) {
+		case string:
+			if v == "" {
+				v = "-"
+			}
+			buf.WriteString(v)
+		case int:
+			buf.WriteString(strconv.Itoa(v))
+		case float64:
+			buf.WriteString(strconv.FormatFloat(v, 'f', 3, 64))
+		}
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
+
+func accessLogValue(c *Context, name string, latency time.Duration) interface{} {
+	req := c.req
+
+	switch name {
+	case "time_local":
+		return c.start.Format(log.DateTimeFormat)
+	case "remote_addr":
+		return c.IP()
+	case "method":
+		return req.Method
+	case "uri":
+		return req.URL.RequestURI()
+	case "path":
+		return req.URL.Path
+	case "query":
+		return req.URL.RawQuery
+	case "protocol":
+		return req.Proto
+	case "host":
+		return req.Host
+	case "status":
+		return c.writer.Status()
+	case "body_bytes_sent":
+		return c.writer.Size()
+	case "request_time":
+		return latency.Seconds()
+	case "request_time_ms":
+		return float64(latency) / float64(time.Millisecond)
+	case "request_id":
+		return c.RequestID()
+	}
+
+	if strings.HasPrefix(name, "http_") {
+		header := strings.Replace(strings.TrimPrefix(name, "http_"), "_", "-", -1)
+		return req.Header.Get(header)
+	}
+
+	return ""
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/json"
+	"lib/log"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 访问日志写入临时目录，返回读取日志的函数
+func newAccessLogService(t *testing.T, format, fields string) (*HttpService, func() string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	l := log.NewLogger()
+	l.SetOutputDir(dir)
+	l.SetOutputByName("access.log")
+
+	s := NewHttpService()
+	s.opts.AccessLog = true
+	s.accessLogger = l
+	s.accessLogFormatter = newAccessLogFormatter(format, fields)
+
+	return s, func() string {
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "access.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+}
+
+func TestResponseWriterCapture(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		handler HandleFunc
+		status  int
+		size    int
+		written bool
+		body    string
+	}{
+		{"no body", nethttp.MethodGet, func(c *Context) {}, 200, 0, false, ""},
+		{"write header only", nethttp.MethodGet, func(c *Context) {
+			c.Writer().WriteHeader(nethttp.StatusNotFound)
+		}, 404, 0, true, ""},
+		{"write header then body", nethttp.MethodGet, func(c *Context) {
+			c.Writer().WriteHeader(nethttp.StatusCreated)
+			c.Writer().WriteHeader(nethttp.StatusBadRequest)
+			_, _ = c.Writer().Write([]byte("created"))
+		}, 201, 7, true, "created"},
+		{"implicit 200", nethttp.MethodGet, func(c *Context) {
+			_, _ = c.Writer().Write([]byte("ok"))
+			_, _ = c.Writer().Write([]byte("ok"))
+		}, 200, 4, true, "okok"},
+		{"head discards body", nethttp.MethodHead, func(c *Context) {
+			_, _ = c.Writer().Write([]byte("ignored"))
+		}, 200, 0, true, ""},
+	}
+
+	for _, tt := range tests {
+		s := NewHttpService()
+		var rw *ResponseWriter
+		s.Get("/", func(c *Context) {
+			tt.handler(c)
+			rw = c.Writer()
+		})
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, "/", nil))
+
+		if rw.Status() != tt.status || rw.Size() != tt.size || rw.Written() != tt.written {
+			t.Errorf("%s: status=%d size=%d written=%v, want %d %d %v",
+				tt.name, rw.Status(), rw.Size(), rw.Written(), tt.status, tt.size, tt.written)
+		}
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: response %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+func TestAccessLogStatusAndSize(t *testing.T) {
+	s, read := newAccessLogService(t, "$method $uri $status $body_bytes_sent", "")
+	s.Get("/empty", func(c *Context) {})
+	s.Get("/missing", func(c *Context) {
+		c.Writer().WriteHeader(nethttp.StatusNotFound)
+	})
+	s.Get("/body", func(c *Context) {
+		c.String(nethttp.StatusAccepted, "hello")
+	})
+
+	for _, path := range []string{"/empty", "/missing", "/body"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(nethttp.MethodGet, path, nil))
+	}
+
+	want := "GET /empty 200 0\nGET /missing 404 0\nGET /body 202 5\n"
+	if got := read(); got != want {
+		t.Errorf("access log = %q, want %q", got, want)
+	}
+}
+
+func TestAccessLogVariables(t *testing.T) {
+	vars := []string{
+		"time_local", "remote_addr", "method", "uri", "path", "query", "protocol", "host",
+		"status", "body_bytes_sent", "request_time", "request_time_ms",
+		"http_referer", "http_user_agent", "request_id", "http_x_token", "unknown",
+	}
+	s, read := newAccessLogService(t, "$"+strings.Join(vars, "|$")+" $$ end", "")
+	s.Get("/users/:id", func(c *Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.String(nethttp.StatusOK, "abc")
+	})
+
+	r := httptest.NewRequest(nethttp.MethodGet, "http://example.com/users/1?a=b", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Referer", "http://ref")
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("X-Token", "secret")
+	r.Header.Set(HeaderXRequestID, "req-1")
+	before := time.Now()
+	s.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := strings.TrimSuffix(read(), "\n")
+	if !strings.HasSuffix(line, " $$ end") {
+		t.Fatalf("literal text not kept: %q", line)
+	}
+	got := strings.Split(strings.TrimSuffix(line, " $$ end"), "|")
+	if len(got) != len(vars) {
+		t.Fatalf("access log = %q, want %d fields", line, len(vars))
+	}
+
+	want := map[string]string{
+		"remote_addr":     "10.0.0.1",
+		"method":          "GET",
+		"uri":             "/users/1?a=b",
+		"path":            "/users/1",
+		"query":           "a=b",
+		"protocol":        "HTTP/1.1",
+		"host":            "example.com",
+		"status":          "200",
+		"body_bytes_sent": "3",
+		"http_referer":    "http://ref",
+		"http_user_agent": "test-agent",
+		"request_id":      "req-1",
+		"http_x_token":    "secret",
+		"unknown":         "-",
+	}
+	for i, name := range vars {
+		if w, ok := want[name]; ok && got[i] != w {
+			t.Errorf("$%s = %q, want %q", name, got[i], w)
+		}
+	}
+
+	if ts, err := time.ParseInLocation(log.DateTimeFormat, got[0], time.Local); err != nil || ts.Before(before.Truncate(time.Second)) {
+		t.Errorf("$time_local = %q, %v", got[0], err)
+	}
+	sec, err := strconv.ParseFloat(got[10], 64)
+	if err != nil || sec < 0.02 || sec > 5 {
+		t.Errorf("$request_time = %q, want >= 0.020", got[10])
+	}
+	ms, err := strconv.ParseFloat(got[11], 64)
+	if err != nil || ms < 20 || ms > 5000 {
+		t.Errorf("$request_time_ms = %q, want >= 20", got[11])
+	}
+}
+
+func TestAccessLogGeneratedRequestID(t *testing.T) {
+	s, read := newAccessLogService(t, "json", "request_id,status,request_time")
+	s.Get("/", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodGet, "/", nil))
+
+	var entry struct {
+		RequestID   string  `json:"request_id"`
+		Status      int     `json:"status"`
+		RequestTime float64 `json:"request_time"`
+	}
+	if err := json.Unmarshal([]byte(read()), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	id := w.Header().Get(HeaderXRequestID)
+	if id == "" || entry.RequestID != id {
+		t.Errorf("request_id = %q, response header = %q", entry.RequestID, id)
+	}
+	if entry.Status != 200 || entry.RequestTime < 0 {
+		t.Errorf("entry = %+v", entry)
+	}
+}
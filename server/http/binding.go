@@ -0,0 +1,285 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	errs "lib/error"
+	"lib/protobuf"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MIMEApplicationJSON = "application/json"
+	MIMEApplicationForm = "application/x-www-form-urlencoded"
+	MIMEMultipartForm   = "multipart/form-data"
+
+	MIMEApplicationProtobuf = protobuf.MIMEType
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// Bind 根据请求方法和 Content-Type 选择解析方式，将参数映射到结构体并校验
+//   - GET、HEAD、DELETE 请求解析 query
+//   - application/json 解析 body
+//   - application/x-www-form-urlencoded、multipart/form-data 解析表单（含 query）
+//   - application/x-protobuf 解析 body，v 需要是 protogen 生成的消息
+//
+// 表单和 query 字段通过 `form:"name"` 标签映射，JSON 使用 `json` 标签
+// 校验规则见 Validate
+func (c *Context) Bind(v interface{}) error {
+	switch c.req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return c.BindQuery(v)
+	}
+
+	switch c.ContentType() {
+	case MIMEApplicationJSON:
+		return c.BindJSON(v)
+	case MIMEApplicationForm, MIMEMultipartForm:
+		return c.BindForm(v)
+	case MIMEApplicationProtobuf:
+		m, ok := v.(protobuf.Message)
+		if !ok {
+			return errs.NewWithCode(errs.CodeBadRequest, "unsupported content type "+MIMEApplicationProtobuf)
+		}
+		return c.BindProto(m)
+	default:
+		return c.BindQuery(v)
+	}
+}
+
+func (c *Context) BindJSON(v interface{}) error {
+	if c.req.Body == nil {
+		return errs.NewWithCode(errs.CodeBadRequest, "empty request body")
+	}
+
+	if err := json.NewDecoder(c.req.Body).Decode(v); err != nil && err != io.EOF {
+		return errs.WrapWithCode(errs.CodeBadRequest, err)
+	}
+
+	return Validate(v)
+}
+
+func (c *Context) BindProto(m protobuf.Message) error {
+	if c.req.Body == nil {
+		return errs.NewWithCode(errs.CodeBadRequest, "empty request body")
+	}
+
+	body, err := ioutil.ReadAll(c.req.Body)
+	if err != nil {
+		return errs.WrapWithCode(errs.CodeBadRequest, err)
+	}
+	if err := protobuf.Unmarshal(body, m); err != nil {
+		return errs.WrapWithCode(errs.CodeBadRequest, err)
+	}
+
+	return Validate(m)
+}
+
+func (c *Context) BindQuery(v interface{}) error {
+	return bindValues(v, c.req.URL.Query(), nil)
+}
+
+func (c *Context) BindForm(v interface{}) error {
+	req := c.req
+	if err := req.ParseMultipartForm(c.httpServer.opts.GetMaxPostMemory()); err != nil {
+		if err != http.ErrNotMultipart {
+			return errs.WrapWithCode(errs.CodeBadRequest, err)
+		}
+	}
+
+	var files map[string][]*multipart.FileHeader
+	if req.MultipartForm != nil {
+		files = req.MultipartForm.File
+	}
+
+	return bindValues(v, req.Form, files)
+}
+
+// 不含参数的 Content-Type，如 "application/json"
+func (c *Context) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// 类型转换失败和校验失败的字段合并到同一个错误中返回
+func bindValues(v interface{}, values url.Values, files map[string][]*multipart.FileHeader) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("bind: target must be a non-nil pointer to struct")
+	}
+
+	bindErr := errs.NewWithCode(errs.CodeBadRequest, "invalid params")
+	mapStruct(rv.Elem(), values, files, bindErr)
+	if err := validateStruct(rv.Elem(), "", bindErr); err != nil {
+		return err
+	}
+	if len(bindErr.Fields()) > 0 {
+		return bindErr
+	}
+
+	return nil
+}
+
+func mapStruct(rv reflect.Value, values url.Values, files map[string][]*multipart.FileHeader, bindErr *errs.Error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			mapStruct(fv, values, files, bindErr)
+			continue
+		}
+
+		if !fv.CanSet() {
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if field.Type == fileHeaderType {
+			if fhs := files[name]; len(fhs) > 0 {
+				fv.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem() == fileHeaderType {
+			if fhs := files[name]; len(fhs) > 0 {
+				fv.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		if err := setField(fv, field, vals); err != nil {
+			bindErr.AddField(name, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+	}
+}
+
+func setField(fv reflect.Value, field reflect.StructField, vals []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type() != reflect.TypeOf([]byte(nil)) {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), field, val); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setValue(fv, field, vals[0])
+}
+
+func setValue(fv reflect.Value, field reflect.StructField, val string) error {
+	if fv.Kind() == reflect.Ptr {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), field, val); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		if val == "" {
+			return nil
+		}
+		layout := field.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.ParseInLocation(layout, val, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected layout %q", val, layout)
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", val)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", val)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", val)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if val == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", val)
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		if val == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(strings.ToLower(val))
+		if err != nil {
+			// 兼容复选框的 "on"
+			if val != "on" {
+				return fmt.Errorf("invalid boolean %q", val)
+			}
+			b = true
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package http
+
+import (
+	"bytes"
+	errs "lib/error"
+	"mime/multipart"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindTarget struct {
+	Name    string                  `form:"name" validate:"required,max=5"`
+	Age     int                     `form:"age" validate:"min=1"`
+	Score   float64                 `form:"score"`
+	Count   uint8                   `form:"count"`
+	Active  bool                    `form:"active"`
+	Agree   bool                    `form:"agree"`
+	Born    time.Time               `form:"born" time_format:"2006-01-02"`
+	Timeout time.Duration           `form:"timeout"`
+	Tags    []string                `form:"tag"`
+	IDs     []int                   `form:"id"`
+	Ref     *int                    `form:"ref"`
+	Skip    string                  `form:"-"`
+	Avatar  *multipart.FileHeader   `form:"avatar"`
+	Docs    []*multipart.FileHeader `form:"doc"`
+}
+
+func newTestContext(r *nethttp.Request) *Context {
+	s := &HttpService{opts: &Options{}}
+	c := newContext(s)
+	c.reset(httptest.NewRecorder(), r)
+	return c
+}
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+	e, ok := err.(*errs.Error)
+	if !ok {
+		t.Fatalf("expected *error.Error, got %T: %v", err, err)
+	}
+	if e.Code() != errs.CodeBadRequest {
+		t.Errorf("expected code %d, got %d", errs.CodeBadRequest, e.Code())
+	}
+
+	fields := make(map[string]string)
+	for _, f := range e.Fields() {
+		fields[f.Field] = f.Message
+	}
+	return fields
+}
+
+func TestBindQueryConversion(t *testing.T) {
+	q := "name=bob&age=3&score=1.5&count=255&active=TRUE&agree=on&born=2020-01-02" +
+		"&timeout=1m30s&tag=a&tag=b&id=1&id=2&ref=7&Skip=x"
+	c := newTestContext(httptest.NewRequest(nethttp.MethodGet, "/?"+q, nil))
+
+	var v bindTarget
+	if err := c.Bind(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	ref := 7
+	expected := bindTarget{
+		Name:    "bob",
+		Age:     3,
+		Score:   1.5,
+		Count:   255,
+		Active:  true,
+		Agree:   true,
+		Born:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local),
+		Timeout: 90 * time.Second,
+		Tags:    []string{"a", "b"},
+		IDs:     []int{1, 2},
+		Ref:     &ref,
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %+v, got %+v", expected, v)
+	}
+}
+
+func TestBindConversionErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		fields []string
+	}{
+		{"name=bob&age=x", []string{"age"}},
+		{"name=bob&count=256", []string{"count"}},
+		{"name=bob&active=maybe", []string{"active"}},
+		{"name=bob&born=2020/01/02", []string{"born"}},
+		{"name=bob&timeout=10", []string{"timeout"}},
+		{"name=bob&id=1&id=b", []string{"id"}},
+		{"name=bob&ref=x", []string{"ref"}},
+		// 类型转换失败和校验失败合并返回
+		{"age=x", []string{"age", "name"}},
+		{"name=toolong&age=-1", []string{"age", "name"}},
+	}
+
+	for _, tt := range tests {
+		c := newTestContext(httptest.NewRequest(nethttp.MethodGet, "/?"+tt.query, nil))
+
+		var v bindTarget
+		fields := fieldErrors(t, c.Bind(&v))
+		if len(fields) != len(tt.fields) {
+			t.Errorf("%s: expected errors on %v, got %v", tt.query, tt.fields, fields)
+			continue
+		}
+		for _, name := range tt.fields {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("%s: expected error on %s, got %v", tt.query, name, fields)
+			}
+		}
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "bob")
+	_ = mw.WriteField("tag", "a")
+	for _, f := range [][2]string{{"avatar", "a.png"}, {"doc", "1.txt"}, {"doc", "2.txt"}} {
+		w, _ := mw.CreateFormFile(f[0], f[1])
+		_, _ = w.Write([]byte(f[1]))
+	}
+	_ = mw.Close()
+
+	r := httptest.NewRequest(nethttp.MethodPost, "/?age=2", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	c := newTestContext(r)
+
+	var v bindTarget
+	if err := c.Bind(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "bob" || v.Age != 2 || !reflect.DeepEqual(v.Tags, []string{"a"}) {
+		t.Errorf("unexpected fields %+v", v)
+	}
+	if v.Avatar == nil || v.Avatar.Filename != "a.png" {
+		t.Errorf("unexpected avatar %+v", v.Avatar)
+	}
+	if len(v.Docs) != 2 || v.Docs[0].Filename != "1.txt" || v.Docs[1].Filename != "2.txt" {
+		t.Errorf("unexpected docs %+v", v.Docs)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	type target struct {
+		Name string `json:"name" validate:"required"`
+		Role string `json:"role" validate:"enum=admin|user"`
+	}
+
+	tests := []struct {
+		body   string
+		fields []string
+		err    bool
+	}{
+		{`{"name":"bob","role":"user"}`, nil, false},
+		{`{"role":"root"}`, []string{"name", "role"}, false},
+		{`{"name":`, nil, true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(nethttp.MethodPost, "/", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+		c := newTestContext(r)
+
+		var v target
+		err := c.Bind(&v)
+		if tt.err {
+			if e, ok := err.(*errs.Error); !ok || e.Code() != errs.CodeBadRequest {
+				t.Errorf("%s: expected bad request, got %v", tt.body, err)
+			}
+			continue
+		}
+
+		fields := fieldErrors(t, err)
+		if len(fields) != len(tt.fields) {
+			t.Errorf("%s: expected errors on %v, got %v", tt.body, tt.fields, fields)
+		}
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	type inner struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	tests := []struct {
+		name  string
+		v     interface{}
+		field string
+	}{
+		{"required empty", &struct {
+			Name string `validate:"required"`
+		}{}, "Name"},
+		{"required zero int", &struct {
+			N int `validate:"required"`
+		}{}, "N"},
+		{"required nil pointer", &struct {
+			P *int `validate:"required"`
+		}{}, "P"},
+		{"required empty slice", &struct {
+			S []string `validate:"required"`
+		}{S: []string{}}, "S"},
+
+		// 除 required 外的规则跳过零值，min=1 不会拒绝 0
+		{"min skips zero", &struct {
+			N int `validate:"min=1"`
+		}{N: 0}, ""},
+		{"min skips empty string", &struct {
+			S string `validate:"min=3"`
+		}{}, ""},
+		{"required and min", &struct {
+			N int `validate:"required,min=1"`
+		}{N: 0}, "N"},
+		{"min negative", &struct {
+			N int `validate:"min=1"`
+		}{N: -1}, "N"},
+		{"max int", &struct {
+			N int `validate:"max=10"`
+		}{N: 11}, "N"},
+		{"max float", &struct {
+			F float64 `validate:"max=1.5"`
+		}{F: 1.5}, ""},
+		{"max runes", &struct {
+			S string `validate:"max=5"`
+		}{S: "héllo"}, ""},
+		{"max string", &struct {
+			S string `validate:"max=5"`
+		}{S: "abcdef"}, "S"},
+		{"min slice", &struct {
+			S []int `validate:"min=2"`
+		}{S: []int{1}}, "S"},
+		{"pointer value", &struct {
+			P *int `validate:"max=1"`
+		}{P: new(int)}, ""},
+
+		{"enum ok", &struct {
+			S string `validate:"enum=a|b"`
+		}{S: "b"}, ""},
+		{"enum fail", &struct {
+			S string `validate:"enum=a|b"`
+		}{S: "c"}, "S"},
+		{"enum int", &struct {
+			N int `validate:"enum=1|2"`
+		}{N: 3}, "N"},
+		{"enum slice", &struct {
+			S []string `validate:"enum=a|b"`
+		}{S: []string{"a", "c"}}, "S"},
+
+		// regexp 中的逗号不会被当作规则分隔符
+		{"regexp ok", &struct {
+			S string `validate:"required,regexp=^[a-z]{1,3}$"`
+		}{S: "abc"}, ""},
+		{"regexp fail", &struct {
+			S string `validate:"required,regexp=^[a-z]{1,3}$"`
+		}{S: "abcd"}, "S"},
+
+		{"field name from tag", &struct {
+			S string `form:"user_name" validate:"required"`
+		}{}, "user_name"},
+		{"nested", &struct {
+			Inner inner `json:"inner"`
+		}{}, "inner.code"},
+		{"nested pointer", &struct {
+			Inner *inner `json:"inner"`
+		}{Inner: &inner{}}, "inner.code"},
+		{"nil nested pointer", &struct {
+			Inner *inner `json:"inner"`
+		}{}, ""},
+	}
+
+	for _, tt := range tests {
+		fields := fieldErrors(t, Validate(tt.v))
+		if tt.field == "" {
+			if len(fields) != 0 {
+				t.Errorf("%s: expected no error, got %v", tt.name, fields)
+			}
+			continue
+		}
+		if _, ok := fields[tt.field]; !ok || len(fields) != 1 {
+			t.Errorf("%s: expected error on %s, got %v", tt.name, tt.field, fields)
+		}
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	type badRule struct {
+		S string `validate:"email"`
+	}
+	type badLimit struct {
+		N int `validate:"min=x"`
+	}
+	type badRegexp struct {
+		S string `validate:"regexp=[a-"`
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"unknown rule", &badRule{S: "x"}, "http.badRule.S: unknown rule 'email'"},
+		// 零值同样返回错误，不依赖请求参数
+		{"invalid limit", &badLimit{}, "http.badLimit.N: invalid min value 'x'"},
+		{"invalid regexp", &badRegexp{S: "a"}, "http.badRegexp.S: invalid regexp"},
+		{"nested", &struct{ Inner badRule }{}, "http.badRule.S: unknown rule 'email'"},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			err := Validate(tt.v)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+			}
+			if _, ok := err.(*errs.Error); ok {
+				t.Errorf("%s: tag error should not be a 400 *error.Error", tt.name)
+			}
+		}
+	}
+
+	r := httptest.NewRequest(nethttp.MethodGet, "/?S=x", nil)
+	if err := newTestContext(r).BindQuery(&badRule{}); err == nil || !strings.Contains(err.Error(), "unknown rule") {
+		t.Errorf("BindQuery: got %v", err)
+	}
+}
@@ -1,24 +1,38 @@
 package http
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"lib/log"
+	"lib/protobuf"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	HeaderApplicationJsonCharsetUTF8 = "application/json; charset=utf-8"
 	HeaderTextHtmlCharsetUTF8        = "text/html; charset=utf-8"
+
+	HeaderXRequestID = "X-Request-Id"
 )
 
 type Context struct {
 	httpServer *HttpService
 	req        *http.Request
 	resp       http.ResponseWriter
+	writer     ResponseWriter
 	handles    []HandleFunc
+	params     Params
 	step       int
+	start      time.Time
+	requestID  string
+	sse        *SSEStream
+	jsonrpc    *jsonrpcCall
 }
 
 func newContext(server *HttpService) *Context {
@@ -30,41 +44,72 @@ func newContext(server *HttpService) *Context {
 }
 
 func (c *Context) reset(w http.ResponseWriter, r *http.Request) *Context {
+	c.writer.reset(w, r)
 	c.req = r
-	c.resp = w
+	c.resp = &c.writer
 	c.handles = make([]HandleFunc, 0)
+	c.params = nil
 	c.step = 0
+	c.start = time.Now()
+	c.requestID = ""
+	c.sse = nil
+	c.jsonrpc = nil
 	return c
 }
 
 func (c *Context) handleHTTPRequest() {
-	var status int
 	defer func() {
 		if c.httpServer.opts.AccessLog {
-			c.httpServer.accessLogger.Print(
-				c.req.Method,
-				status,
-				c.req.URL.RequestURI(),
-				c.IP())
+			line := c.httpServer.accessLogFormatter.Format(c, time.Since(c.start))
+			_, _ = c.httpServer.accessLogger.Write(line)
 		}
 	}()
+	defer c.recoverPanic()
+	defer c.closeSSE()
 
-	if _, ok := HttpMethods[c.req.Method]; !ok {
-		status = 405
-		c.handles = append(c.handles, catchHandles(status))
-		return
+	c.SetHeader(HeaderXRequestID, c.RequestID())
+
+	handles := c.match()
+
+	// 全局中间件对所有请求生效，包括 404 和 405
+	c.handles = combineHandles(c.httpServer.middlewares, handles)
+
+	c.Next()
+}
+
+// 查找路由
+// HEAD 请求未注册时使用 GET 路由，OPTIONS 请求未注册时返回允许的方法
+// 路径存在但方法不匹配时返回 405 并设置 Allow 头
+func (c *Context) match() []HandleFunc {
+	router := c.httpServer.router
+	method, path := c.req.Method, c.req.URL.Path
+
+	if _, ok := HttpMethods[method]; ok {
+		if handles, params := router.Get(method, path); handles != nil {
+			c.params = params
+			return handles
+		}
+
+		if method == http.MethodHead {
+			if handles, params := router.Get(http.MethodGet, path); handles != nil {
+				c.params = params
+				return handles
+			}
+		}
 	}
 
-	handles := c.httpServer.router.Get(c.req.Method, c.req.URL.Path)
-	if handles == nil {
-		status = 404
-		c.handles = append(c.handles, catchHandles(status))
-	} else {
-		status = 200
-		c.handles = handles
+	allowed := router.Allowed(path)
+	if len(allowed) == 0 {
+		return []HandleFunc{catchHandles(http.StatusNotFound)}
 	}
 
-	c.Next()
+	allow := strings.Join(allowed, ", ")
+	if method == http.MethodOptions {
+		return []HandleFunc{optionsHandle(allow)}
+	}
+
+	c.SetHeader("Allow", allow)
+	return []HandleFunc{catchHandles(http.StatusMethodNotAllowed)}
 }
 
 func (c *Context) Next() {
@@ -78,6 +123,31 @@ func (c *Context) Next() {
 	c.handles[i](c)
 }
 
+func (c *Context) Request() *http.Request {
+	return c.req
+}
+
+// Writer 返回包装后的 ResponseWriter，可获取已写入的状态码和字节数
+func (c *Context) Writer() *ResponseWriter {
+	return &c.writer
+}
+
+// RequestID 优先使用请求头 "X-Request-Id"，不存在时生成
+func (c *Context) RequestID() string {
+	if c.requestID == "" {
+		c.requestID = c.GetHeader(HeaderXRequestID)
+		if c.requestID == "" {
+			c.requestID = newRequestID()
+		}
+	}
+	return c.requestID
+}
+
+// Context 返回携带请求 ID 的 context，通过 client/http 调用下游服务时请求 ID 会自动透传
+func (c *Context) Context() context.Context {
+	return log.ContextWithRequestID(c.req.Context(), c.RequestID())
+}
+
 func (c *Context) Log() *log.Logger {
 	return c.httpServer.Log()
 }
@@ -112,8 +182,8 @@ func (c *Context) IP() string {
 }
 
 func (c *Context) String(code int, text string) {
-	c.resp.WriteHeader(code)
 	c.resp.Header().Set("Content-Type", HeaderTextHtmlCharsetUTF8)
+	c.resp.WriteHeader(code)
 	_, err := c.resp.Write([]byte(text))
 	c.Error(err)
 }
@@ -130,8 +200,22 @@ func (c *Context) JSON(code int, v interface{}) {
 		return
 	}
 
-	c.resp.WriteHeader(code)
 	c.resp.Header().Set("Content-Type", HeaderApplicationJsonCharsetUTF8)
+	c.resp.WriteHeader(code)
+	_, err = c.resp.Write(body)
+	c.Error(err)
+}
+
+// Proto 以 application/x-protobuf 格式输出消息
+func (c *Context) Proto(code int, m protobuf.Message) {
+	body, err := protobuf.Marshal(m)
+	if err != nil {
+		c.httpServer.logger.Error(err)
+		return
+	}
+
+	c.resp.Header().Set("Content-Type", protobuf.MIMEType)
+	c.resp.WriteHeader(code)
 	_, err = c.resp.Write(body)
 	c.Error(err)
 }
@@ -172,6 +256,12 @@ func (c *Context) Get(key string) string {
 	return c.req.URL.Query().Get(key)
 }
 
+// 获取路由参数，如 "/users/:id" 中的 id
+func (c *Context) Param(name string) string {
+	value, _ := c.params.Get(name)
+	return value
+}
+
 func (c *Context) Post(key string) string {
 	if values, exists := c.PostArray(key); exists {
 		return values[0]
@@ -185,3 +275,11 @@ func (c *Context) Error(err error) {
 	}
 	c.Log().Error("Err:", err)
 }
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	errs "lib/error"
+	"net/http"
+	"sync"
+)
+
+// HandleErrorFunc 可以返回错误的处理函数，错误交给 ErrorHandler 统一处理
+// 注册路由时需要通过 WrapErrorFunc 转换为 HandleFunc
+type HandleErrorFunc func(c *Context) error
+
+// ErrorHandler 处理请求过程中产生的错误，包括 panic、404 和 405
+// 可以通过 HttpService.SetErrorHandler 替换，输出统一的错误格式
+type ErrorHandler func(c *Context, err error)
+
+// Envelope 统一的响应格式
+type Envelope struct {
+	Code      errs.ErrorCode `json:"code"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"request_id"`
+	Data      interface{}    `json:"data,omitempty"`
+}
+
+var (
+	errorStatusMu sync.RWMutex
+	errorStatus   = make(map[errs.ErrorCode]int)
+)
+
+// RegisterErrorStatus 注册业务错误码对应的 HTTP 状态码
+func RegisterErrorStatus(code errs.ErrorCode, status int) {
+	errorStatusMu.Lock()
+	defer errorStatusMu.Unlock()
+
+	errorStatus[code] = status
+}
+
+// ErrorStatus 获取错误对应的 HTTP 状态码
+// 优先使用 RegisterErrorStatus 注册的映射，*error.Error 的错误码在 400-599 之间时直接作为状态码，否则为 500
+func ErrorStatus(err error) int {
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return http.StatusInternalServerError
+	}
+
+	errorStatusMu.RLock()
+	status, ok := errorStatus[e.Code()]
+	errorStatusMu.RUnlock()
+	if ok {
+		return status
+	}
+
+	if code := int(e.Code()); code >= 400 && code < 600 {
+		return code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// SetErrorHandler 替换默认的错误处理
+func (s *HttpService) SetErrorHandler(h ErrorHandler) {
+	s.errorHandler = h
+}
+
+// 默认错误处理，以 Envelope 格式输出
+// 非 *error.Error 的错误不对外暴露错误信息；响应已写入时只记录日志
+func defaultErrorHandler(c *Context, err error) {
+	status := ErrorStatus(err)
+	if status >= http.StatusInternalServerError {
+		c.Log().WithRequestID(c.RequestID()).WithError(err).Error("request:", c.req.Method, c.req.URL.RequestURI())
+	}
+
+	if c.writer.Written() {
+		return
+	}
+
+	envelope := Envelope{
+		Code:      errs.ErrorCode(status),
+		Message:   HttpStatus[status],
+		RequestID: c.RequestID(),
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		if e.Code() != errs.CodeOK {
+			envelope.Code = e.Code()
+		}
+		envelope.Message = e.Message()
+		if fields := e.Fields(); len(fields) > 0 {
+			envelope.Data = fields
+		}
+	}
+
+	c.JSON(status, envelope)
+}
+
+// AbortWithError 中断后续处理，并将错误交给 ErrorHandler 处理
+// JSON-RPC 调用中错误作为该次调用的 error 返回
+func (c *Context) AbortWithError(err error) {
+	c.Break()
+	if c.jsonrpc != nil {
+		c.jsonrpc.err = err
+		return
+	}
+	c.httpServer.handleError(c, err)
+}
+
+// Success 以 Envelope 格式输出成功响应
+func (c *Context) Success(data interface{}) {
+	c.JSON(http.StatusOK, Envelope{
+		Code:      errs.CodeOK,
+		Message:   "ok",
+		RequestID: c.RequestID(),
+		Data:      data,
+	})
+}
+
+// WrapErrorFunc 将 HandleErrorFunc 转换为 HandleFunc，如：
+//
+//	s.Get("/users/:id", http.WrapErrorFunc(func(c *http.Context) error { ... }))
+func WrapErrorFunc(h HandleErrorFunc) HandleFunc {
+	return func(c *Context) {
+		if err := h(c); err != nil {
+			c.AbortWithError(err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 子进程通过该环境变量得知需要接管父进程的监听套接字
+// 套接字以 ExtraFiles 的方式传递，在子进程中的文件描述符固定为 3
+const envInheritListener = "TYRION_INHERIT_LISTENER"
+
+const inheritListenerFd = 3
+
+// 子进程开始服务后向该管道写入一个字节通知父进程，文件描述符固定为 4
+const envReadyPipe = "TYRION_READY_PIPE"
+
+const readyPipeFd = 4
+
+// 等待子进程就绪的最长时间，超时后结束子进程，父进程继续服务
+const restartReadyTimeout = 30 * time.Second
+
+// 优先接管父进程传递的监听套接字，否则监听配置的地址
+func (service *HttpService) listen() (net.Listener, error) {
+	var (
+		ln  net.Listener
+		err error
+	)
+
+	if os.Getenv(envInheritListener) != "" {
+		_ = os.Unsetenv(envInheritListener)
+
+		if os.Getenv(envReadyPipe) != "" {
+			_ = os.Unsetenv(envReadyPipe)
+			service.readyPipe = os.NewFile(readyPipeFd, "ready")
+		}
+
+		f := os.NewFile(inheritListenerFd, "listener")
+		ln, err = net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		service.logger.Info("inherited listener:", ln.Addr().String())
+	} else {
+		addr := service.server.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+
+	service.listener = ln
+	return ln, nil
+}
+
+// 通知父进程已经开始服务，非平滑重启启动时忽略
+func (service *HttpService) notifyReady() {
+	if service.readyPipe == nil {
+		return
+	}
+
+	if _, err := service.readyPipe.Write([]byte{1}); err != nil {
+		service.logger.Error("notify parent ready:", err)
+	}
+	_ = service.readyPipe.Close()
+	service.readyPipe = nil
+}
+
+// 以相同的参数启动新进程，并将监听套接字传递给新进程
+// 新进程开始服务后才返回，旧进程随后优雅关闭，端口始终处于监听状态
+// 新进程启动失败、退出或超时未就绪时返回错误，旧进程继续服务
+func (service *HttpService) restart() (int, error) {
+	tl, ok := service.listener.(*net.TCPListener)
+	if !ok {
+		return 0, errors.New("listener is not a tcp listener")
+	}
+
+	f, err := tl.File()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	path, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	// 父进程持有读端，子进程持有写端
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envInheritListener+"=") && !strings.HasPrefix(kv, envReadyPipe+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, envInheritListener+"=1", envReadyPipe+"=1")
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	cmd.ExtraFiles = []*os.File{f, w}
+
+	err = cmd.Start()
+	// 关闭父进程中的写端，子进程退出后读端才能收到 EOF
+	_ = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		_, err := r.Read(b)
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+		if err == nil {
+			return cmd.Process.Pid, nil
+		}
+		err = errors.New("new process exited before ready")
+	case <-time.After(restartReadyTimeout):
+		err = errors.New("new process not ready in " + restartReadyTimeout.String())
+		_ = cmd.Process.Kill()
+	}
+
+	// 回收子进程，避免僵尸进程
+	_ = cmd.Wait()
+	return 0, err
+}
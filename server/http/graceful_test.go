@@ -0,0 +1,102 @@
+//go:build !windows
+
+package http
+
+import (
+	"context"
+	"io"
+	nethttp "net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 平滑重启时由 restart 以子进程方式运行，接管父进程的监听套接字并处理一个请求
+func TestGracefulRestartChild(t *testing.T) {
+	if os.Getenv(envInheritListener) == "" {
+		t.Skip("only run as child of TestGracefulRestart")
+	}
+
+	s := NewHttpService()
+	served := make(chan struct{})
+	s.Get("/pid", func(c *Context) {
+		c.String(nethttp.StatusOK, strconv.Itoa(os.Getpid()))
+		close(served)
+	})
+
+	s.setServerOpts()
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.readyPipe == nil {
+		t.Fatal("ready pipe not inherited")
+	}
+	go s.server.Serve(ln)
+	s.notifyReady()
+
+	select {
+	case <-served:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no request received")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGracefulRestart(t *testing.T) {
+	if os.Getenv(envInheritListener) != "" {
+		t.Skip("running as child")
+	}
+
+	// 子进程以相同参数启动，只运行 TestGracefulRestartChild
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestGracefulRestartChild$"}
+	defer func() { os.Args = args }()
+
+	s := NewHttpService()
+	s.setServerOpts()
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	pid, err := s.restart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid == os.Getpid() {
+		t.Fatalf("restart returned own pid %d", pid)
+	}
+
+	// 父进程不再接收连接，请求只能由子进程处理
+	_ = ln.Close()
+
+	resp, err := nethttp.Get("http://" + addr + "/pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != strconv.Itoa(pid) {
+		t.Errorf("served by pid %s, want child %d", body, pid)
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := p.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Success() {
+		t.Errorf("child exited with %v", state)
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RouterGroup 路由分组
+// 分组可以继续嵌套，前缀和中间件会逐级叠加
+type RouterGroup struct {
+	httpServer  *HttpService
+	prefix      string
+	middlewares []HandleFunc
+}
+
+func newRouterGroup(server *HttpService, prefix string, middlewares []HandleFunc) *RouterGroup {
+	return &RouterGroup{
+		httpServer:  server,
+		prefix:      joinPath("", prefix),
+		middlewares: combineHandles(nil, middlewares),
+	}
+}
+
+// Group 创建子分组
+func (g *RouterGroup) Group(prefix string, middlewares ...HandleFunc) *RouterGroup {
+	return &RouterGroup{
+		httpServer:  g.httpServer,
+		prefix:      joinPath(g.prefix, prefix),
+		middlewares: combineHandles(g.middlewares, middlewares),
+	}
+}
+
+// Use 为分组追加中间件，只对之后注册的路由生效
+func (g *RouterGroup) Use(middlewares ...HandleFunc) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+func (g *RouterGroup) Prefix() string {
+	return g.prefix
+}
+
+func (g *RouterGroup) Any(pattern string, h ...HandleFunc) {
+	g.add(http.MethodGet, pattern, h)
+	g.add(http.MethodPost, pattern, h)
+	g.add(http.MethodPut, pattern, h)
+	g.add(http.MethodDelete, pattern, h)
+}
+
+func (g *RouterGroup) Get(pattern string, h ...HandleFunc) {
+	g.add(http.MethodGet, pattern, h)
+}
+
+func (g *RouterGroup) Post(pattern string, h ...HandleFunc) {
+	g.add(http.MethodPost, pattern, h)
+}
+
+func (g *RouterGroup) Put(pattern string, h ...HandleFunc) {
+	g.add(http.MethodPut, pattern, h)
+}
+
+func (g *RouterGroup) Delete(pattern string, h ...HandleFunc) {
+	g.add(http.MethodDelete, pattern, h)
+}
+
+func (g *RouterGroup) Patch(pattern string, h ...HandleFunc) {
+	g.add(http.MethodPatch, pattern, h)
+}
+
+func (g *RouterGroup) Head(pattern string, h ...HandleFunc) {
+	g.add(http.MethodHead, pattern, h)
+}
+
+func (g *RouterGroup) Options(pattern string, h ...HandleFunc) {
+	g.add(http.MethodOptions, pattern, h)
+}
+
+// AddLogic 在分组下注册 Logic 路由
+func (g *RouterGroup) AddLogic(prefix string, logic Logic, middlewares ...HandleFunc) {
+	g.httpServer.addLogic(g.add, prefix, logic, middlewares)
+}
+
+func (g *RouterGroup) add(method string, pattern string, handles []HandleFunc) {
+	g.httpServer.add(method, joinPath(g.prefix, pattern), combineHandles(g.middlewares, handles))
+}
+
+func joinPath(prefix, pattern string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if pattern == "" {
+		return prefix
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	return prefix + pattern
+}
@@ -0,0 +1,406 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHubClosed    = errors.New("hub: closed")
+	ErrHubQueueFull = errors.New("hub: send queue full")
+)
+
+const defaultHubSendQueueSize = 256
+
+// HubOptions Hub 配置
+type HubOptions struct {
+	// 每个连接的发送队列长度，队列满时视为慢消费者并断开连接，默认 256
+	SendQueueSize int
+
+	// 发送 ping 的间隔，0 表示不发送
+	PingInterval time.Duration
+}
+
+// Hub 管理 WebSocket 连接，支持按连接、用户、房间发送消息和广播
+//
+//	hub := s.NewHub(http.HubOptions{PingInterval: 30 * time.Second})
+//	hub.OnMessage(func(client *http.HubClient, messageType int, data []byte) { ... })
+//	s.WebSocket("/ws", func(c *http.Context, conn *http.WSConn) {
+//		hub.Serve(conn, c.Get("uid"))
+//	})
+type Hub struct {
+	mu      sync.RWMutex
+	opts    HubOptions
+	closed  bool
+	clients map[string]*HubClient
+	users   map[string]map[string]*HubClient
+	rooms   map[string]map[string]*HubClient
+
+	onConnect    func(client *HubClient)
+	onDisconnect func(client *HubClient)
+	onMessage    func(client *HubClient, messageType int, data []byte)
+}
+
+// HubClient Hub 中的连接
+type HubClient struct {
+	ID     string
+	UserID string
+
+	hub   *Hub
+	conn  *WSConn
+	send  chan hubMessage
+	done  chan struct{}
+	once  sync.Once
+	rooms map[string]struct{}
+}
+
+type hubMessage struct {
+	messageType int
+	data        []byte
+}
+
+func NewHub(opts HubOptions) *Hub {
+	if opts.SendQueueSize <= 0 {
+		opts.SendQueueSize = defaultHubSendQueueSize
+	}
+
+	return &Hub{
+		opts:    opts,
+		clients: make(map[string]*HubClient),
+		users:   make(map[string]map[string]*HubClient),
+		rooms:   make(map[string]map[string]*HubClient),
+	}
+}
+
+// NewHub 创建 Hub，服务关闭时以 1001 关闭所有连接，超过关闭超时时间后强制断开
+func (s *HttpService) NewHub(opts HubOptions) *Hub {
+	hub := NewHub(opts)
+	s.shutdownHooks = append(s.shutdownHooks, hub.Shutdown)
+	return hub
+}
+
+func (h *Hub) OnConnect(fn func(client *HubClient)) {
+	h.onConnect = fn
+}
+
+func (h *Hub) OnDisconnect(fn func(client *HubClient)) {
+	h.onDisconnect = fn
+}
+
+func (h *Hub) OnMessage(fn func(client *HubClient, messageType int, data []byte)) {
+	h.onMessage = fn
+}
+
+// Serve 将连接加入 Hub 并循环读取消息，连接断开后返回
+// userID 可以为空
+func (h *Hub) Serve(conn *WSConn, userID string) error {
+	client := &HubClient{
+		ID:     newRequestID(),
+		UserID: userID,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan hubMessage, h.opts.SendQueueSize),
+		done:   make(chan struct{}),
+		rooms:  make(map[string]struct{}),
+	}
+
+	if err := h.register(client); err != nil {
+		_ = conn.Close(CloseGoingAway, "server shutting down")
+		return err
+	}
+	defer h.unregister(client)
+
+	go client.writeLoop(h.opts.PingInterval)
+
+	if h.onConnect != nil {
+		h.onConnect(client)
+	}
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-client.done:
+				// 由 Hub 主动关闭
+				return nil
+			default:
+			}
+
+			client.close(CloseNormalClosure, "")
+			if _, ok := err.(*CloseError); ok {
+				return nil
+			}
+			return err
+		}
+
+		if h.onMessage != nil {
+			h.onMessage(client, messageType, data)
+		}
+	}
+}
+
+func (h *Hub) register(client *HubClient) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	h.clients[client.ID] = client
+	if client.UserID != "" {
+		if h.users[client.UserID] == nil {
+			h.users[client.UserID] = make(map[string]*HubClient)
+		}
+		h.users[client.UserID][client.ID] = client
+	}
+
+	return nil
+}
+
+func (h *Hub) unregister(client *HubClient) {
+	h.mu.Lock()
+	delete(h.clients, client.ID)
+	if client.UserID != "" {
+		delete(h.users[client.UserID], client.ID)
+		if len(h.users[client.UserID]) == 0 {
+			delete(h.users, client.UserID)
+		}
+	}
+	for room := range client.rooms {
+		h.leaveLocked(client, room)
+	}
+	h.mu.Unlock()
+
+	if h.onDisconnect != nil {
+		h.onDisconnect(client)
+	}
+}
+
+func (h *Hub) leaveLocked(client *HubClient, room string) {
+	delete(client.rooms, room)
+	delete(h.rooms[room], client.ID)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// Client 按 ID 获取连接
+func (h *Hub) Client(id string) (*HubClient, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	client, ok := h.clients[id]
+	return client, ok
+}
+
+// Count 当前连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients)
+}
+
+// RoomClients 房间内的连接
+func (h *Hub) RoomClients(room string) []*HubClient {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*HubClient, 0, len(h.rooms[room]))
+	for _, client := range h.rooms[room] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// Broadcast 向所有连接发送消息
+func (h *Hub) Broadcast(messageType int, data []byte) {
+	h.mu.RLock()
+	clients := make([]*HubClient, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	sendAll(clients, messageType, data)
+}
+
+// BroadcastRoom 向房间内的所有连接发送消息
+func (h *Hub) BroadcastRoom(room string, messageType int, data []byte) {
+	sendAll(h.RoomClients(room), messageType, data)
+}
+
+// SendToUser 向用户的所有连接发送消息
+func (h *Hub) SendToUser(userID string, messageType int, data []byte) {
+	h.mu.RLock()
+	clients := make([]*HubClient, 0, len(h.users[userID]))
+	for _, client := range h.users[userID] {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	sendAll(clients, messageType, data)
+}
+
+// Close 以 1001 关闭所有连接，之后不再接受新连接
+func (h *Hub) Close() {
+	h.Shutdown(context.Background())
+}
+
+// Shutdown 并发关闭所有连接，ctx 结束时直接断开还未关闭完成的连接
+func (h *Hub) Shutdown(ctx context.Context) {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*HubClient, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *HubClient) {
+			defer wg.Done()
+			client.close(CloseGoingAway, "server shutting down")
+		}(client)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for _, client := range clients {
+			_ = client.conn.closeConn()
+		}
+	}
+}
+
+func sendAll(clients []*HubClient, messageType int, data []byte) {
+	for _, client := range clients {
+		_ = client.Send(messageType, data)
+	}
+}
+
+// Join 加入房间
+func (c *HubClient) Join(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c.ID]; !ok {
+		return
+	}
+
+	c.rooms[room] = struct{}{}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[string]*HubClient)
+	}
+	h.rooms[room][c.ID] = c
+}
+
+// Leave 离开房间
+func (c *HubClient) Leave(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	c.hub.leaveLocked(c, room)
+}
+
+// Rooms 已加入的房间
+func (c *HubClient) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+func (c *HubClient) Conn() *WSConn {
+	return c.conn
+}
+
+// Send 将消息放入发送队列，队列已满时断开连接并返回 ErrHubQueueFull
+func (c *HubClient) Send(messageType int, data []byte) error {
+	select {
+	case <-c.done:
+		return ErrWSClosed
+	default:
+	}
+
+	select {
+	case c.send <- hubMessage{messageType: messageType, data: data}:
+		return nil
+	default:
+		// 慢消费者，异步关闭避免阻塞广播
+		go c.close(ClosePolicyViolation, "slow consumer")
+		return ErrHubQueueFull
+	}
+}
+
+func (c *HubClient) SendText(text string) error {
+	return c.Send(TextMessage, []byte(text))
+}
+
+func (c *HubClient) SendJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(TextMessage, data)
+}
+
+// Close 关闭连接
+func (c *HubClient) Close() {
+	c.close(CloseNormalClosure, "")
+}
+
+func (c *HubClient) close(code int, text string) {
+	c.once.Do(func() {
+		close(c.done)
+		if code == CloseAbnormalClosure {
+			// 连接已不可写，直接关闭
+			_ = c.conn.closeConn()
+		} else {
+			_ = c.conn.Close(code, text)
+		}
+	})
+}
+
+func (c *HubClient) writeLoop(pingInterval time.Duration) {
+	var tick <-chan time.Time
+	if pingInterval > 0 {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			if err := c.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				c.close(CloseAbnormalClosure, "")
+				return
+			}
+		case <-tick:
+			if err := c.conn.Ping(nil); err != nil {
+				c.close(CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func waitHubCount(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for hub.Count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d clients, want %d", hub.Count(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubShutdown(t *testing.T) {
+	hub := NewHub(HubOptions{})
+	disconnected := make(chan string, 3)
+	hub.OnDisconnect(func(client *HubClient) {
+		disconnected <- client.UserID
+	})
+
+	var frames []chan serverFrame
+	served := make(chan error, 3)
+	for _, uid := range []string{"a", "b", "c"} {
+		ws, _, f := newPipeWSConn(t)
+		frames = append(frames, f)
+		go func(ws *WSConn, uid string) {
+			served <- hub.Serve(ws, uid)
+		}(ws, uid)
+	}
+	waitHubCount(t, hub, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	hub.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown took %v", elapsed)
+	}
+
+	for _, f := range frames {
+		expectClose(t, f, CloseGoingAway)
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-served; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+		<-disconnected
+	}
+	if n := hub.Count(); n != 0 {
+		t.Errorf("hub has %d clients after shutdown", n)
+	}
+
+	// 关闭后不再接受新连接
+	ws, _, f := newPipeWSConn(t)
+	if err := hub.Serve(ws, "d"); err != ErrHubClosed {
+		t.Errorf("Serve after shutdown = %v, want %v", err, ErrHubClosed)
+	}
+	expectClose(t, f, CloseGoingAway)
+}
+
+func TestHubShutdownDeadline(t *testing.T) {
+	hub := NewHub(HubOptions{})
+
+	// 对端不读取数据，发送消息时写操作阻塞并持有写锁
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := newWSConn(server, bufio.NewReader(server), nil)
+	ws.maxMessageSize = defaultWebSocketMaxMessageSize
+
+	go func() {
+		_ = hub.Serve(ws, "")
+	}()
+	waitHubCount(t, hub, 1)
+	hub.Broadcast(TextMessage, []byte("blocked"))
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	hub.Shutdown(ctx)
+
+	// 在关闭写超时之前返回，并直接断开连接
+	if elapsed := time.Since(start); elapsed >= closeWriteTimeout {
+		t.Errorf("Shutdown took %v, want it to stop at the ctx deadline", elapsed)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.Copy(io.Discard, client); err != nil {
+		t.Errorf("connection not closed: %v", err)
+	}
+}
+
+func TestHubShutdownWithService(t *testing.T) {
+	s := NewHttpService()
+	hub := s.NewHub(HubOptions{})
+
+	ws, _, f := newPipeWSConn(t)
+	served := make(chan error, 1)
+	go func() {
+		served <- hub.Serve(ws, "")
+	}()
+	waitHubCount(t, hub, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expectClose(t, f, CloseGoingAway)
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
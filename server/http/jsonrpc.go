@@ -0,0 +1,355 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	errs "lib/error"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// JSON-RPC 2.0 错误码，见 https://www.jsonrpc.org/specification#error_object
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+
+	// 处理函数没有返回错误，但输出了 4xx、5xx 状态码
+	JSONRPCServerError = -32000
+)
+
+const (
+	jsonrpcVersion     = "2.0"
+	jsonrpcMaxBatch    = 100
+	jsonrpcMaxBodySize = 4 << 20
+)
+
+// JSONRPCError JSON-RPC 错误对象，处理函数返回该类型的错误时原样输出
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+// JSONRPC JSON-RPC 2.0 端点，通过 HttpService.JSONRPC 创建
+//
+// 方法通过 AddLogic 或 Handle 注册，每次调用使用独立的 Context：
+// params 作为 JSON 请求体，通过 c.Bind 或 c.BindJSON 解析；处理函数以 JSON 格式输出的内容作为 result，
+// 返回的错误转换为 error 对象：
+//   - 参数解析和校验失败（错误码 400）为 -32602
+//   - *JSONRPCError 原样输出
+//   - 其他 *error.Error 使用其错误码和错误信息
+//   - 其余错误和 panic 为 -32603，不对外暴露错误信息
+//
+// 支持批量请求和通知（不带 id 的请求），通知不返回响应，全部为通知时响应 204
+type JSONRPC struct {
+	httpServer *HttpService
+	methods    map[string][]HandleFunc
+}
+
+type jsonrpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  json.RawMessage `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// 单次调用的状态，处理函数中的错误不交给 ErrorHandler，而是记录在这里
+type jsonrpcCall struct {
+	err error
+}
+
+// JSONRPC 在 pattern 上注册 JSON-RPC 端点，只响应 POST 请求
+// 全局中间件、分组中间件以及 middlewares 对整个 HTTP 请求执行一次，可以在其中完成鉴权和日志
+func (s *HttpService) JSONRPC(pattern string, middlewares ...HandleFunc) *JSONRPC {
+	j := &JSONRPC{httpServer: s, methods: make(map[string][]HandleFunc)}
+	s.Post(pattern, combineHandles(middlewares, []HandleFunc{j.handle})...)
+	return j
+}
+
+// JSONRPC 在分组下注册 JSON-RPC 端点
+func (g *RouterGroup) JSONRPC(pattern string, middlewares ...HandleFunc) *JSONRPC {
+	j := &JSONRPC{httpServer: g.httpServer, methods: make(map[string][]HandleFunc)}
+	g.Post(pattern, combineHandles(middlewares, []HandleFunc{j.handle})...)
+	return j
+}
+
+// AddLogic 将 Logic 的方法注册为 JSON-RPC 方法，方法名为 "namespace.MethodName"，namespace 为空时为 "MethodName"
+// 方法签名与 HttpService.AddLogic 相同；middlewares 和 Logic 的 Middlewares() 在每次调用时执行
+func (j *JSONRPC) AddLogic(namespace string, logic Logic, middlewares ...HandleFunc) {
+	logic.Init()
+
+	if l, ok := logic.(LogicWithMiddlewares); ok {
+		middlewares = combineHandles(l.Middlewares(), middlewares)
+	}
+
+	v := reflect.ValueOf(logic)
+	t := v.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if logicReserved[m.Name] || !isLogicAction(v.Method(i).Type()) {
+			continue
+		}
+
+		name := m.Name
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		j.register(name, combineHandles(wrapHandles(middlewares), []HandleFunc{j.httpServer.wrapLogic(v.Method(i))}))
+	}
+}
+
+// Handle 注册单个 JSON-RPC 方法
+func (j *JSONRPC) Handle(method string, h ...HandleFunc) {
+	j.register(method, wrapHandles(h))
+}
+
+func (j *JSONRPC) register(method string, handles []HandleFunc) {
+	if method == "" || strings.HasPrefix(method, "rpc.") {
+		panic("http: invalid json-rpc method '" + method + "'")
+	}
+	if _, ok := j.methods[method]; ok {
+		panic("http: json-rpc method '" + method + "' already registered")
+	}
+	j.methods[method] = handles
+}
+
+func (j *JSONRPC) handle(c *Context) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.resp, c.req.Body, jsonrpcMaxBodySize))
+	if err != nil {
+		c.JSON(http.StatusOK, newJSONRPCError(nil, JSONRPCInvalidRequest, err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			c.JSON(http.StatusOK, newJSONRPCError(nil, JSONRPCParseError, "Parse error"))
+			return
+		}
+		if len(batch) == 0 || len(batch) > jsonrpcMaxBatch {
+			c.JSON(http.StatusOK, newJSONRPCError(nil, JSONRPCInvalidRequest, "Invalid Request"))
+			return
+		}
+
+		responses := make([]*jsonrpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := j.call(c, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+
+		if len(responses) == 0 {
+			c.resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.JSON(http.StatusOK, responses)
+		return
+	}
+
+	if !json.Valid(body) {
+		c.JSON(http.StatusOK, newJSONRPCError(nil, JSONRPCParseError, "Parse error"))
+		return
+	}
+
+	resp := j.call(c, body)
+	if resp == nil {
+		c.resp.WriteHeader(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 执行一个请求，通知返回 nil
+func (j *JSONRPC) call(c *Context, raw json.RawMessage) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || !isJSONRPCID(req.ID) {
+		return newJSONRPCError(nil, JSONRPCInvalidRequest, "Invalid Request")
+	}
+
+	var method string
+	if req.Version != jsonrpcVersion || json.Unmarshal(req.Method, &method) != nil || method == "" {
+		return newJSONRPCError(req.ID, JSONRPCInvalidRequest, "Invalid Request")
+	}
+
+	// params 只能是对象或数组
+	params := bytes.TrimSpace(req.Params)
+	if string(params) == "null" {
+		params = nil
+	}
+	if len(params) > 0 && params[0] != '{' && params[0] != '[' {
+		return newJSONRPCError(req.ID, JSONRPCInvalidRequest, "Invalid Request")
+	}
+
+	notification := req.ID == nil
+
+	handles, ok := j.methods[method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return newJSONRPCError(req.ID, JSONRPCMethodNotFound, "Method not found")
+	}
+
+	result, rpcErr := j.invoke(c, method, handles, params)
+	if notification {
+		return nil
+	}
+	if rpcErr != nil {
+		return &jsonrpcResponse{Version: jsonrpcVersion, Error: rpcErr, ID: jsonrpcID(req.ID)}
+	}
+	return &jsonrpcResponse{Version: jsonrpcVersion, Result: result, ID: req.ID}
+}
+
+// 以 params 作为请求体，在独立的 Context 中执行处理函数
+func (j *JSONRPC) invoke(parent *Context, method string, handles []HandleFunc, params []byte) (json.RawMessage, *JSONRPCError) {
+	req := parent.req.Clone(parent.req.Context())
+	req.Method = http.MethodPost
+	req.Header.Set("Content-Type", MIMEApplicationJSON)
+	req.Body = ioutil.NopCloser(bytes.NewReader(params))
+	req.ContentLength = int64(len(params))
+	req.Form, req.PostForm, req.MultipartForm = nil, nil, nil
+
+	rec := &jsonrpcRecorder{header: make(http.Header)}
+	c := j.httpServer.pool.Get().(*Context)
+	defer j.httpServer.pool.Put(c)
+
+	c.reset(rec, req)
+	c.params = parent.params
+	c.requestID = parent.RequestID()
+	c.jsonrpc = &jsonrpcCall{}
+	c.handles = handles
+
+	func() {
+		defer c.recoverPanic()
+		c.Next()
+	}()
+
+	if err := c.jsonrpc.err; err != nil {
+		return nil, j.toError(c, method, err)
+	}
+
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if status := c.writer.Status(); status >= http.StatusBadRequest {
+		e := &JSONRPCError{Code: JSONRPCServerError, Message: HttpStatus[status]}
+		if len(body) > 0 {
+			e.Data = jsonrpcResult(rec, body)
+		}
+		return nil, e
+	}
+
+	if len(body) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return jsonrpcResult(rec, body), nil
+}
+
+func (j *JSONRPC) toError(c *Context, method string, err error) *JSONRPCError {
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	if ErrorStatus(err) >= http.StatusInternalServerError {
+		c.Log().WithRequestID(c.RequestID()).WithError(err).Error("jsonrpc:", method)
+		return &JSONRPCError{Code: JSONRPCInternalError, Message: "Internal error"}
+	}
+
+	var e *errs.Error
+	errors.As(err, &e)
+
+	rpcErr = &JSONRPCError{Code: int(e.Code()), Message: e.Message()}
+	if e.Code() == errs.CodeBadRequest {
+		rpcErr.Code = JSONRPCInvalidParams
+	}
+	if fields := e.Fields(); len(fields) > 0 {
+		rpcErr.Data = fields
+	}
+	return rpcErr
+}
+
+func newJSONRPCError(id json.RawMessage, code int, message string) *jsonrpcResponse {
+	return &jsonrpcResponse{
+		Version: jsonrpcVersion,
+		Error:   &JSONRPCError{Code: code, Message: message},
+		ID:      jsonrpcID(id),
+	}
+}
+
+// 出错时 id 不能省略，无法确定时为 null
+func jsonrpcID(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// id 只能是字符串、数字或 null，省略时为通知
+func isJSONRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+
+	id = bytes.TrimSpace(id)
+	switch {
+	case string(id) == "null":
+		return true
+	case len(id) == 0:
+		return false
+	case id[0] == '"', id[0] == '-', id[0] >= '0' && id[0] <= '9':
+		return true
+	}
+	return false
+}
+
+// 处理函数输出 JSON 时原样作为 result，其他内容作为字符串
+func jsonrpcResult(rec *jsonrpcRecorder, body []byte) json.RawMessage {
+	if strings.HasPrefix(rec.header.Get("Content-Type"), MIMEApplicationJSON) && json.Valid(body) {
+		return append(json.RawMessage{}, body...)
+	}
+
+	result, _ := json.Marshal(string(body))
+	return result
+}
+
+func wrapHandles(handles []HandleFunc) []HandleFunc {
+	wrapped := make([]HandleFunc, 0, len(handles))
+	for _, h := range handles {
+		wrapped = append(wrapped, WrapHandlerFunc(h))
+	}
+	return wrapped
+}
+
+// 记录单次调用的输出
+type jsonrpcRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *jsonrpcRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *jsonrpcRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *jsonrpcRecorder) WriteHeader(code int) {
+}
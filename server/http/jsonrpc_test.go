@@ -0,0 +1,203 @@
+package http
+
+import (
+	"encoding/json"
+	errs "lib/error"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mathLogic struct {
+	notified *int
+}
+
+func (l *mathLogic) Init() {}
+
+type addParams struct {
+	A int `json:"a" validate:"required"`
+	B int `json:"b"`
+}
+
+func (l *mathLogic) Add(c *Context) error {
+	var p addParams
+	if err := c.Bind(&p); err != nil {
+		return err
+	}
+	c.OkJSON(p.A + p.B)
+	return nil
+}
+
+func (l *mathLogic) Notify(c *Context) {
+	*l.notified++
+}
+
+func (l *mathLogic) Empty(c *Context) {}
+
+func (l *mathLogic) Text(c *Context) {
+	c.OkString("plain")
+}
+
+func (l *mathLogic) Denied(c *Context) error {
+	return errs.NewWithCode(errs.CodeForbidden, "denied")
+}
+
+func (l *mathLogic) Custom(c *Context) error {
+	return &JSONRPCError{Code: 1001, Message: "custom", Data: "detail"}
+}
+
+func (l *mathLogic) Boom(c *Context) {
+	panic("boom")
+}
+
+func (l *mathLogic) Status(c *Context) {
+	c.JSON(nethttp.StatusConflict, map[string]string{"reason": "conflict"})
+}
+
+func newJSONRPCTestService(notified *int) *HttpService {
+	s := NewHttpService()
+	s.Use(func(c *Context) {
+		if c.GetHeader("Authorization") != "token" {
+			c.AbortWithError(errs.NewWithCode(errs.CodeUnauthorized, "unauthorized"))
+		}
+	})
+
+	rpc := s.JSONRPC("/rpc")
+	rpc.AddLogic("math", &mathLogic{notified: notified}, func(c *Context) {
+		c.SetHeader("X-Method-Middleware", "1")
+	})
+	rpc.Handle("ping", func(c *Context) { c.OkJSON("pong") })
+	return s
+}
+
+func doJSONRPC(s *HttpService, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(nethttp.MethodPost, "/rpc", strings.NewReader(body))
+	r.Header.Set("Authorization", "token")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestJSONRPCSingle(t *testing.T) {
+	var notified int
+	s := newJSONRPCTestService(&notified)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"result", `{"jsonrpc":"2.0","method":"math.Add","params":{"a":1,"b":2},"id":1}`, `{"jsonrpc":"2.0","result":3,"id":1}`},
+		{"string id", `{"jsonrpc":"2.0","method":"ping","id":"abc"}`, `{"jsonrpc":"2.0","result":"pong","id":"abc"}`},
+		{"null id", `{"jsonrpc":"2.0","method":"ping","id":null}`, `{"jsonrpc":"2.0","result":"pong","id":null}`},
+		{"no output", `{"jsonrpc":"2.0","method":"math.Empty","id":2}`, `{"jsonrpc":"2.0","result":null,"id":2}`},
+		{"text output", `{"jsonrpc":"2.0","method":"math.Text","id":3}`, `{"jsonrpc":"2.0","result":"plain","id":3}`},
+		{"parse error", `{"jsonrpc":"2.0","method"`, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"not an object", `1`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"wrong version", `{"jsonrpc":"1.0","method":"ping","id":4}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":4}`},
+		{"method not string", `{"jsonrpc":"2.0","method":1,"id":5}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":5}`},
+		{"object id", `{"jsonrpc":"2.0","method":"ping","id":{}}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"scalar params", `{"jsonrpc":"2.0","method":"ping","params":1,"id":6}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":6}`},
+		{"method not found", `{"jsonrpc":"2.0","method":"math.Sub","id":7}`, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":7}`},
+		{"reserved method", `{"jsonrpc":"2.0","method":"math.Init","id":8}`, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":8}`},
+		{"invalid params", `{"jsonrpc":"2.0","method":"math.Add","params":{"a":"x"},"id":9}`, ``},
+		{"business error", `{"jsonrpc":"2.0","method":"math.Denied","id":10}`, `{"jsonrpc":"2.0","error":{"code":403,"message":"denied"},"id":10}`},
+		{"custom error", `{"jsonrpc":"2.0","method":"math.Custom","id":11}`, `{"jsonrpc":"2.0","error":{"code":1001,"message":"custom","data":"detail"},"id":11}`},
+		{"panic", `{"jsonrpc":"2.0","method":"math.Boom","id":12}`, `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":12}`},
+		{"error status", `{"jsonrpc":"2.0","method":"math.Status","id":13}`, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"Conflict","data":{"reason":"conflict"}},"id":13}`},
+	}
+
+	for _, tt := range tests {
+		w := doJSONRPC(s, tt.body)
+		if w.Code != nethttp.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", tt.name, w.Code)
+			continue
+		}
+
+		got := strings.TrimSpace(w.Body.String())
+		if tt.want == "" {
+			var resp jsonrpcResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != JSONRPCInvalidParams {
+				t.Errorf("%s: expected invalid params, got %s", tt.name, got)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if w := doJSONRPC(s, `{"jsonrpc":"2.0","method":"math.Add","params":{"a":1},"id":1}`); w.Header().Get("X-Method-Middleware") != "" {
+		t.Error("method middleware should not write to the http response")
+	}
+}
+
+func TestJSONRPCBatchAndNotifications(t *testing.T) {
+	var notified int
+	s := newJSONRPCTestService(&notified)
+
+	w := doJSONRPC(s, `[
+		{"jsonrpc":"2.0","method":"math.Add","params":{"a":1,"b":2},"id":"1"},
+		{"jsonrpc":"2.0","method":"math.Notify","params":{}},
+		{"jsonrpc":"2.0","method":"missing"},
+		{"foo":"boo"},
+		{"jsonrpc":"2.0","method":"math.Add","params":{"a":5,"b":5},"id":"2"}
+	]`)
+	want := `[{"jsonrpc":"2.0","result":3,"id":"1"},` +
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},` +
+		`{"jsonrpc":"2.0","result":10,"id":"2"}]`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("batch: expected %s, got %s", want, got)
+	}
+	if notified != 1 {
+		t.Errorf("expected notification to run once, got %d", notified)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+		want string
+	}{
+		{"all notifications", `[{"jsonrpc":"2.0","method":"math.Notify"},{"jsonrpc":"2.0","method":"math.Notify"}]`, nethttp.StatusNoContent, ""},
+		{"single notification", `{"jsonrpc":"2.0","method":"math.Notify"}`, nethttp.StatusNoContent, ""},
+		{"empty batch", `[]`, nethttp.StatusOK, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"invalid batch", `[1,2]`, nethttp.StatusOK, `[{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]`},
+		{"batch parse error", `[{"jsonrpc":"2.0","method":"ping","id":1},`, nethttp.StatusOK, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+	}
+
+	for _, tt := range tests {
+		w := doJSONRPC(s, tt.body)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.code, w.Code)
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+	if notified != 4 {
+		t.Errorf("expected 4 notifications, got %d", notified)
+	}
+}
+
+func TestJSONRPCMiddlewares(t *testing.T) {
+	var notified int
+	s := newJSONRPCTestService(&notified)
+
+	// 全局中间件对整个 HTTP 请求生效
+	r := httptest.NewRequest(nethttp.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != nethttp.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest(nethttp.MethodGet, "/rpc", nil)
+	r.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != nethttp.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", w.Code)
+	}
+}
@@ -1,5 +1,229 @@
 package http
 
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Logic 业务控制器，通过 AddLogic 注册
+// 导出方法的签名为 func(*Context) 或 func(*Context) error 时注册为路由，默认响应所有请求方法，
+// 如 GetUser 注册为 "ANY prefix/GetUser"
+//
+// 配置 "logic_verb_routing = true" 后，方法名以 HTTP 方法开头时只响应对应的请求方法并去掉前缀，
+// 如 GetUser 注册为 "GET prefix/User"，User 仍注册为 "ANY prefix/User"，GET 请求由 GetUser 处理。
+// 开启后已有 Logic 的路径会发生变化，需要同步调整客户端
+//
+// 两个方法映射到相同的请求方法和路径时注册会 panic，如 kebab 风格下的 HTTPServer 和 HttpServer
 type Logic interface {
 	Init()
 }
+
+// LogicWithRoutes 自定义路由，实现后不再按方法名注册
+type LogicWithRoutes interface {
+	Logic
+	Routes() []LogicRoute
+}
+
+// LogicWithMiddlewares 为 Logic 下的所有路由添加中间件
+type LogicWithMiddlewares interface {
+	Logic
+	Middlewares() []HandleFunc
+}
+
+// LogicRoute Logic 路由
+type LogicRoute struct {
+	// 请求方法，为空或 "ANY" 时响应所有请求方法
+	Method string
+	// 相对于 prefix 的路径，支持路由参数
+	Path string
+	// Logic 的方法名
+	Action string
+}
+
+// 路径风格，通过配置 "logic_path_style" 指定
+const (
+	LogicPathStyleDefault = ""      // 保持方法名，如 UserInfo
+	LogicPathStyleKebab   = "kebab" // 如 user-info
+	LogicPathStyleSnake   = "snake" // 如 user_info
+)
+
+var (
+	contextType = reflect.TypeOf((*Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+
+	// 方法名前缀对应的请求方法
+	logicVerbs = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+	}
+
+	// 不作为路由注册的方法
+	logicReserved = map[string]bool{
+		"Init":        true,
+		"Routes":      true,
+		"Middlewares": true,
+	}
+)
+
+// 注册 Logic 路由，注册前调用 Logic.Init()
+func (s *HttpService) addLogic(add func(method string, pattern string, handles []HandleFunc), prefix string, logic Logic, middlewares []HandleFunc) {
+	logic.Init()
+
+	if l, ok := logic.(LogicWithMiddlewares); ok {
+		middlewares = combineHandles(l.Middlewares(), middlewares)
+	}
+
+	var routes []LogicRoute
+	if l, ok := logic.(LogicWithRoutes); ok {
+		routes = l.Routes()
+	} else {
+		routes = logicRoutes(logic, s.opts.LogicPathStyle, s.opts.LogicVerbRouting)
+	}
+
+	v := reflect.ValueOf(logic)
+	var bindings []*logicBinding
+	registered := make(map[string]*logicBinding)
+	for _, route := range routes {
+		method := v.MethodByName(route.Action)
+		if !method.IsValid() || !isLogicAction(method.Type()) {
+			panic("http: invalid logic action '" + route.Action + "' for " + v.Type().String())
+		}
+
+		pattern := joinPath(prefix, route.Path)
+		handles := combineHandles(middlewares, []HandleFunc{s.wrapLogic(method)})
+
+		var methods []string
+		explicit := true
+		switch strings.ToUpper(route.Method) {
+		case "", "ANY":
+			methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+			explicit = false
+		default:
+			methods = []string{strings.ToUpper(route.Method)}
+		}
+
+		// 指定了请求方法的路由优先于 ANY，如 GetUser 覆盖 User 的 GET 请求
+		for _, m := range methods {
+			b := &logicBinding{method: m, pattern: pattern, action: route.Action, explicit: explicit, handles: handles}
+			key := m + " " + pattern
+			prev, ok := registered[key]
+			switch {
+			case !ok:
+				registered[key] = b
+				bindings = append(bindings, b)
+			case prev.explicit == explicit:
+				panic("http: logic " + v.Type().String() + " actions '" + prev.action + "' and '" + route.Action +
+					"' both register " + key)
+			case explicit:
+				*prev = *b
+			}
+		}
+	}
+
+	for _, b := range bindings {
+		add(b.method, b.pattern, b.handles)
+	}
+}
+
+type logicBinding struct {
+	method   string
+	pattern  string
+	action   string
+	explicit bool
+	handles  []HandleFunc
+}
+
+// 按方法名生成路由，verbRouting 为 true 时按方法名前缀区分请求方法
+func logicRoutes(logic Logic, style string, verbRouting bool) []LogicRoute {
+	t := reflect.TypeOf(logic)
+	v := reflect.ValueOf(logic)
+
+	routes := make([]LogicRoute, 0, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if logicReserved[m.Name] || !isLogicAction(v.Method(i).Type()) {
+			continue
+		}
+
+		method, name := "", m.Name
+		if verbRouting {
+			method, name = splitLogicVerb(m.Name)
+		}
+		routes = append(routes, LogicRoute{
+			Method: method,
+			Path:   "/" + convertPathStyle(name, style),
+			Action: m.Name,
+		})
+	}
+
+	return routes
+}
+
+// func(*Context) 或 func(*Context) error
+func isLogicAction(t reflect.Type) bool {
+	if t.NumIn() != 1 || t.In(0) != contextType {
+		return false
+	}
+
+	switch t.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return t.Out(0) == errorType
+	}
+
+	return false
+}
+
+// GetUser => GET, User
+// Getter => ANY, Getter
+func splitLogicVerb(name string) (string, string) {
+	for _, verb := range logicVerbs {
+		prefix := verb[:1] + strings.ToLower(verb[1:])
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		rest := name[len(prefix):]
+		if rest == "" || unicode.IsUpper(rune(rest[0])) {
+			return verb, rest
+		}
+	}
+
+	return "", name
+}
+
+// UserInfo => user-info / user_info，HTTPServer => http-server
+func convertPathStyle(name string, style string) string {
+	var sep string
+	switch style {
+	case LogicPathStyleKebab:
+		sep = "-"
+	case LogicPathStyleSnake:
+		sep = "_"
+	default:
+		return name
+	}
+
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteString(sep)
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	errs "lib/error"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type userLogic struct {
+	inited bool
+}
+
+func (l *userLogic) Init() { l.inited = true }
+
+func (l *userLogic) User(c *Context)       { c.OkString("User") }
+func (l *userLogic) GetUser(c *Context)    { c.OkString("GetUser") }
+func (l *userLogic) DeleteUser(c *Context) { c.OkString("DeleteUser") }
+func (l *userLogic) UserInfo(c *Context)   { c.OkString("UserInfo") }
+func (l *userLogic) Getter(c *Context)     { c.OkString("Getter") }
+
+func (l *userLogic) Missing(c *Context) error {
+	return errs.NewWithCode(errs.CodeNotFound, "user not found")
+}
+
+// 签名不符，不注册为路由
+func (l *userLogic) Name() string { return "user" }
+
+type customRoutesLogic struct{}
+
+func (l *customRoutesLogic) Init() {}
+
+func (l *customRoutesLogic) Routes() []LogicRoute {
+	return []LogicRoute{
+		{Method: "get", Path: "/users/:id", Action: "Show"},
+		{Path: "/users", Action: "List"},
+	}
+}
+
+func (l *customRoutesLogic) Show(c *Context) { c.OkString("Show " + c.Param("id")) }
+func (l *customRoutesLogic) List(c *Context) { c.OkString("List") }
+
+type duplicateLogic struct{}
+
+func (l *duplicateLogic) Init()                 {}
+func (l *duplicateLogic) HTTPServer(c *Context) {}
+func (l *duplicateLogic) HttpServer(c *Context) {}
+
+func serveLogic(s *HttpService, method, path string) (int, string) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w.Code, w.Body.String()
+}
+
+func TestLogicRoutes(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		verb  bool
+		want  map[string]string // Action => "METHOD /path"
+	}{
+		{"default", LogicPathStyleDefault, false, map[string]string{
+			"User": " /User", "GetUser": " /GetUser", "UserInfo": " /UserInfo", "Getter": " /Getter",
+		}},
+		{"kebab", LogicPathStyleKebab, false, map[string]string{
+			"GetUser": " /get-user", "UserInfo": " /user-info",
+		}},
+		{"snake", LogicPathStyleSnake, false, map[string]string{
+			"DeleteUser": " /delete_user", "UserInfo": " /user_info",
+		}},
+		{"verb", LogicPathStyleDefault, true, map[string]string{
+			"User": " /User", "GetUser": "GET /User", "DeleteUser": "DELETE /User", "Getter": " /Getter",
+		}},
+		{"verb kebab", LogicPathStyleKebab, true, map[string]string{
+			"GetUser": "GET /user", "UserInfo": " /user-info",
+		}},
+	}
+
+	for _, tt := range tests {
+		got := make(map[string]string)
+		for _, r := range logicRoutes(&userLogic{}, tt.style, tt.verb) {
+			got[r.Action] = r.Method + " " + r.Path
+		}
+		if _, ok := got["Name"]; ok {
+			t.Errorf("%s: Name should not be registered", tt.name)
+		}
+		if _, ok := got["Init"]; ok {
+			t.Errorf("%s: Init should not be registered", tt.name)
+		}
+		for action, want := range tt.want {
+			if got[action] != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, action, got[action], want)
+			}
+		}
+	}
+}
+
+func TestConvertPathStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		kebab string
+		snake string
+	}{
+		{"User", "user", "user"},
+		{"UserInfo", "user-info", "user_info"},
+		{"HTTPServer", "http-server", "http_server"},
+		{"GetV2Info", "get-v2-info", "get_v2_info"},
+		{"ID", "id", "id"},
+	}
+
+	for _, tt := range tests {
+		if got := convertPathStyle(tt.name, LogicPathStyleKebab); got != tt.kebab {
+			t.Errorf("kebab %s = %q, want %q", tt.name, got, tt.kebab)
+		}
+		if got := convertPathStyle(tt.name, LogicPathStyleSnake); got != tt.snake {
+			t.Errorf("snake %s = %q, want %q", tt.name, got, tt.snake)
+		}
+		if got := convertPathStyle(tt.name, LogicPathStyleDefault); got != tt.name {
+			t.Errorf("default %s = %q", tt.name, got)
+		}
+	}
+}
+
+func TestAddLogicVerbRouting(t *testing.T) {
+	s := NewHttpService()
+	s.opts.LogicVerbRouting = true
+	s.opts.LogicPathStyle = LogicPathStyleKebab
+
+	logic := &userLogic{}
+	s.AddLogic("/api", logic)
+	if !logic.inited {
+		t.Error("Init not called")
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		// GET 由 GetUser 处理，其他方法由 User 处理
+		{nethttp.MethodGet, "/api/user", 200, "GetUser"},
+		{nethttp.MethodPost, "/api/user", 200, "User"},
+		{nethttp.MethodDelete, "/api/user", 200, "DeleteUser"},
+		{nethttp.MethodPut, "/api/user-info", 200, "UserInfo"},
+		{nethttp.MethodGet, "/api/getter", 200, "Getter"},
+		{nethttp.MethodGet, "/api/get-user", 404, ""},
+		{nethttp.MethodGet, "/api/name", 404, ""},
+		{nethttp.MethodGet, "/api/init", 404, ""},
+		{nethttp.MethodGet, "/api/missing", 404, "user not found"},
+	}
+
+	for _, tt := range tests {
+		code, body := serveLogic(s, tt.method, tt.path)
+		if code != tt.code || !strings.Contains(body, tt.body) {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, code, body, tt.code, tt.body)
+		}
+	}
+}
+
+func TestAddLogicCustomRoutes(t *testing.T) {
+	s := NewHttpService()
+	s.Group("/v1").AddLogic("/admin", &customRoutesLogic{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{nethttp.MethodGet, "/v1/admin/users/7", 200, "Show 7"},
+		{nethttp.MethodPost, "/v1/admin/users/7", 405, ""},
+		{nethttp.MethodPost, "/v1/admin/users", 200, "List"},
+		// 实现 Routes 后不再按方法名注册
+		{nethttp.MethodGet, "/v1/admin/Show", 404, ""},
+	}
+
+	for _, tt := range tests {
+		code, body := serveLogic(s, tt.method, tt.path)
+		if code != tt.code || !strings.Contains(body, tt.body) {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, code, body, tt.code, tt.body)
+		}
+	}
+}
+
+func TestAddLogicDuplicateRoute(t *testing.T) {
+	s := NewHttpService()
+	s.opts.LogicPathStyle = LogicPathStyleKebab
+
+	defer func() {
+		r := recover()
+		msg, _ := r.(string)
+		for _, want := range []string{"duplicateLogic", "HTTPServer", "HttpServer", "/api/http-server"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("panic %v, want it to contain %q", r, want)
+			}
+		}
+	}()
+
+	s.AddLogic("/api", &duplicateLogic{})
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	errs "lib/error"
+	"lib/log"
+)
+
+type logLevelRequest struct {
+	Name  string `json:"name" form:"name"`
+	Level string `json:"level" form:"level" validate:"required"`
+}
+
+// LogLevel 注册查看和修改日志级别的管理接口，需要通过 middlewares 鉴权，如：
+//
+//	s.LogLevel("/admin/log/level", auth)
+//
+// GET 返回所有通过 log.Register 注册的 Logger 的级别，如 {"default":"info","access":"print"}
+// PUT 修改级别，如 {"name":"access","level":"debug"}，name 为空时修改默认 Logger
+func (s *HttpService) LogLevel(pattern string, middlewares ...HandleFunc) {
+	s.Get(pattern, combineHandles(middlewares, []HandleFunc{getLogLevels})...)
+	s.Put(pattern, combineHandles(middlewares, []HandleFunc{WrapErrorFunc(setLogLevel)})...)
+}
+
+// LogLevel 在分组下注册日志级别管理接口
+func (g *RouterGroup) LogLevel(pattern string, middlewares ...HandleFunc) {
+	g.Get(pattern, combineHandles(middlewares, []HandleFunc{getLogLevels})...)
+	g.Put(pattern, combineHandles(middlewares, []HandleFunc{WrapErrorFunc(setLogLevel)})...)
+}
+
+func getLogLevels(c *Context) {
+	c.Success(logLevels())
+}
+
+func setLogLevel(c *Context) error {
+	var req logLevelRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if req.Name == "" {
+		req.Name = log.DefaultName
+	}
+
+	level, err := log.ParseLevel(req.Level)
+	if err != nil {
+		return errs.NewWithCode(errs.CodeBadRequest, err.Error())
+	}
+	if err := log.SetLevelByName(req.Name, level); err != nil {
+		return errs.NewWithCode(errs.CodeNotFound, err.Error())
+	}
+
+	c.Log().Warn("log level of", req.Name, "changed to", level, "by", c.IP())
+	c.Success(logLevels())
+	return nil
+}
+
+func logLevels() map[string]string {
+	levels := make(map[string]string)
+	for _, name := range log.Names() {
+		if l := log.Get(name); l != nil {
+			levels[name] = l.Level().String()
+		}
+	}
+	return levels
+}
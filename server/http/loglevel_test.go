@@ -0,0 +1,59 @@
+package http
+
+import (
+	errs "lib/error"
+	"lib/log"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	l := log.NewLogger()
+	l.SetLevel(log.INFO)
+	log.Register("loglevel.test", l)
+
+	s := NewHttpService()
+	s.LogLevel("/admin/log/level", func(c *Context) {
+		if c.GetHeader("Authorization") != "token" {
+			c.AbortWithError(errs.NewWithCode(errs.CodeUnauthorized, "unauthorized"))
+		}
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		want   string
+	}{
+		{"get", nethttp.MethodGet, "", nethttp.StatusOK, `"loglevel.test":"info"`},
+		{"set", nethttp.MethodPut, `{"name":"loglevel.test","level":"DEBUG"}`, nethttp.StatusOK, `"loglevel.test":"debug"`},
+		{"missing level", nethttp.MethodPut, `{"name":"loglevel.test"}`, nethttp.StatusBadRequest, ""},
+		{"invalid level", nethttp.MethodPut, `{"name":"loglevel.test","level":"verbose"}`, nethttp.StatusBadRequest, "unknown level"},
+		{"unknown logger", nethttp.MethodPut, `{"name":"missing","level":"info"}`, nethttp.StatusNotFound, "unknown logger"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/admin/log/level", strings.NewReader(tt.body))
+		r.Header.Set("Authorization", "token")
+		r.Header.Set("Content-Type", MIMEApplicationJSON)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected %d containing %q, got %d %s", tt.name, tt.code, tt.want, w.Code, w.Body.String())
+		}
+	}
+	if l.Level() != log.DEBUG {
+		t.Errorf("expected level to be changed, got %s", l.Level())
+	}
+
+	r := httptest.NewRequest(nethttp.MethodGet, "/admin/log/level", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != nethttp.StatusUnauthorized {
+		t.Errorf("expected middlewares to guard the endpoint, got %d", w.Code)
+	}
+}
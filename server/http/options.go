@@ -48,6 +48,13 @@ func (o *Options) ResetOpts(opts *Options) *Options {
 	return opts
 }
 
+func (opt *Options) GetShutdownTimeout() time.Duration {
+	if opt.ShutdownTimeoutMs <= 0 {
+		return time.Duration(30) * time.Second
+	}
+	return time.Duration(opt.ShutdownTimeoutMs) * time.Millisecond
+}
+
 func (opt *Options) GetMaxPostMemory() int64 {
 	return 0
 }
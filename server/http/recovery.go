@@ -0,0 +1,31 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// 恢复处理函数中的 panic，记录堆栈并交给 ErrorHandler 处理
+func (c *Context) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	// 与 net/http 保持一致，主动中断的请求不做处理
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+
+	var err error
+	switch v := r.(type) {
+	case error:
+		err = v
+	default:
+		err = fmt.Errorf("%v", v)
+	}
+
+	c.Log().Errorf("panic recovered: %s %s, err: %v\n%s", c.req.Method, c.req.URL.RequestURI(), err, debug.Stack())
+	c.AbortWithError(err)
+}
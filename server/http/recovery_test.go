@@ -0,0 +1,107 @@
+package http
+
+import (
+	"encoding/json"
+	errs "lib/error"
+	"lib/log"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	tests := []struct {
+		name    string
+		panic   interface{}
+		status  int
+		code    errs.ErrorCode
+		message string
+	}{
+		{"string", "boom", 500, 500, HttpStatus[500]},
+		{"plain error", os.ErrClosed, 500, 500, HttpStatus[500]},
+		{"error code", errs.NewWithCode(errs.CodeTooManyRequests, "slow down"), 429, 429, "slow down"},
+		{"business code", errs.NewWithCode(10001, "quota exceeded"), 500, 10001, "quota exceeded"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		l := log.NewLogger()
+		l.SetOutputDir(dir)
+		l.SetOutputByName("error.log")
+
+		s := NewHttpService()
+		s.logger = l
+		s.Get("/panic", func(c *Context) {
+			panic(tt.panic)
+		})
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodGet, "/panic", nil))
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+
+		var env Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+			t.Fatalf("%s: body %q: %v", tt.name, w.Body.String(), err)
+		}
+		if env.Code != tt.code || env.Message != tt.message {
+			t.Errorf("%s: envelope = %+v, want code %d message %q", tt.name, env, tt.code, tt.message)
+		}
+		if id := w.Header().Get(HeaderXRequestID); id == "" || env.RequestID != id {
+			t.Errorf("%s: request_id = %q, header = %q", tt.name, env.RequestID, id)
+		}
+
+		_ = l.Flush()
+		b, err := os.ReadFile(filepath.Join(dir, "error.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logged := string(b)
+		if !strings.Contains(logged, "panic recovered: GET /panic") {
+			t.Errorf("%s: panic not logged: %q", tt.name, logged)
+		}
+		if !strings.Contains(logged, "runtime/debug.Stack") || !strings.Contains(logged, "recovery_test.go") {
+			t.Errorf("%s: stack not logged: %q", tt.name, logged)
+		}
+	}
+}
+
+func TestRecoverPanicAfterWrite(t *testing.T) {
+	s := NewHttpService()
+	s.logger = log.NewLogger()
+	s.logger.SetOutputDir(t.TempDir())
+	s.logger.SetOutputByName("error.log")
+	s.Get("/partial", func(c *Context) {
+		c.String(nethttp.StatusAccepted, "partial")
+		panic("late")
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodGet, "/partial", nil))
+
+	// 响应已写入时不再输出错误信息
+	if w.Code != nethttp.StatusAccepted || w.Body.String() != "partial" {
+		t.Errorf("response = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoverErrAbortHandler(t *testing.T) {
+	s := NewHttpService()
+	s.Get("/abort", func(c *Context) {
+		panic(nethttp.ErrAbortHandler)
+	})
+
+	defer func() {
+		if r := recover(); r != nethttp.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+		}
+	}()
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(nethttp.MethodGet, "/abort", nil))
+	t.Error("ErrAbortHandler was swallowed")
+}
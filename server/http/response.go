@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 包装 http.ResponseWriter，记录状态码、写入字节数以及 Header 是否已发送
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	status      int
+	size        int
+	wroteHeader bool
+
+	// HEAD 请求不输出 body
+	discardBody bool
+}
+
+func (w *ResponseWriter) reset(rw http.ResponseWriter, r *http.Request) {
+	w.ResponseWriter = rw
+	w.status = http.StatusOK
+	w.size = 0
+	w.wroteHeader = false
+	w.discardBody = r.Method == http.MethodHead
+}
+
+// 重复调用时忽略，避免 "superfluous WriteHeader call"
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = code
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.discardBody {
+		return len(b), nil
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// 响应状态码，未写入时为 200
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+// 已写入 body 的字节数
+func (w *ResponseWriter) Size() int {
+	return w.size
+}
+
+// Header 是否已发送
+func (w *ResponseWriter) Written() bool {
+	return w.wroteHeader
+}
+
+func (w *ResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http: response writer does not support hijacking")
+	}
+
+	// 连接被接管后由调用方负责写入
+	w.wroteHeader = true
+	return hijacker.Hijack()
+}
+
+// 供 http.ResponseController 使用
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
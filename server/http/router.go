@@ -1,6 +1,7 @@
 package http
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -51,33 +52,84 @@ var (
 
 type Router struct {
 	httpServer *HttpService
-	handles    map[int]map[string][]HandleFunc
+	trees      map[int]*node
 }
 
 func newRouter(server *HttpService) *Router {
 	r := new(Router)
 	r.httpServer = server
-	r.handles = make(map[int]map[string][]HandleFunc)
+	r.trees = make(map[int]*node)
 	for _, method := range HttpMethods {
-		r.handles[method] = make(map[string][]HandleFunc)
+		r.trees[method] = newNode()
 	}
 	return r
 }
 
+// 注册路由，支持命名参数和通配符，如 "/users/:id"、"/static/*filepath"
+// 静态路径段不区分大小写
 func (r *Router) Register(method string, pattern string, handles []HandleFunc) {
-	pattern = strings.TrimRight(strings.ToLower(pattern), "/")
-	r.handles[HttpMethods[method]][pattern] = handles
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	if pattern != "/" {
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	m, ok := HttpMethods[method]
+	if !ok {
+		panic("http: unsupported method '" + method + "'")
+	}
+
+	r.trees[m].addRoute(pattern, handles)
 }
 
-func (r *Router) Get(method string, pattern string) []HandleFunc {
-	pattern = strings.ToLower(pattern)
-	if r.httpServer.opts.IgnorePathLastSlash {
-		pattern = strings.TrimRight(pattern, "/")
+func (r *Router) Get(method string, path string) ([]HandleFunc, Params) {
+	if r.httpServer.opts.IgnorePathLastSlash && path != "/" {
+		path = strings.TrimRight(path, "/")
+	}
+
+	m, ok := HttpMethods[method]
+	if !ok {
+		return nil, nil
+	}
+
+	if leaf, params := r.trees[m].getRoute(path); leaf != nil {
+		return leaf.handles, params
+	}
+
+	return nil, nil
+}
+
+// Allowed 返回路径已注册的请求方法，路径不存在时返回空
+// 注册了 GET 时包含 HEAD，存在任意方法时包含 OPTIONS
+func (r *Router) Allowed(path string) []string {
+	if r.httpServer.opts.IgnorePathLastSlash && path != "/" {
+		path = strings.TrimRight(path, "/")
+	}
+
+	exists := make(map[string]bool)
+	for method, m := range HttpMethods {
+		if leaf, _ := r.trees[m].getRoute(path); leaf != nil {
+			exists[method] = true
+		}
+	}
+
+	if len(exists) == 0 {
+		return nil
+	}
+
+	if exists["GET"] {
+		exists["HEAD"] = true
 	}
+	exists["OPTIONS"] = true
 
-	if handles, exists := r.handles[HttpMethods[method]][pattern]; exists {
-		return handles
+	allowed := make([]string, 0, len(exists))
+	for method := range exists {
+		allowed = append(allowed, method)
 	}
+	sort.Slice(allowed, func(i, j int) bool {
+		return HttpMethods[allowed[i]] < HttpMethods[allowed[j]]
+	})
 
-	return nil
+	return allowed
 }
@@ -1,25 +1,50 @@
 package http
 
 import (
+	"context"
 	"lib/config"
 	"lib/core"
+	errs "lib/error"
 	"lib/log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
 	"sync"
+	"syscall"
 )
 
 type HandleFunc func(c *Context)
 
+// HttpService HTTP 服务
+// Get、Post、Group 等注册方法只接受 HandleFunc，返回 error 的处理函数（HandleErrorFunc）
+// 需要先用 WrapErrorFunc 转换，如 s.Get("/users/:id", http.WrapErrorFunc(getUser))
+// AddLogic 注册的 Logic 方法两种签名都支持，无需转换
 type HttpService struct {
 	core.App
 
-	opts         *Options
-	router       *Router
-	server       *http.Server
-	logger       *log.Logger
-	accessLogger *log.Logger
-	pool         sync.Pool
+	opts               *Options
+	router             *Router
+	server             *http.Server
+	listener           net.Listener
+	readyPipe          *os.File
+	logger             *log.Logger
+	accessLogger       *log.Logger
+	accessLogFormatter *accessLogFormatter
+	middlewares        []HandleFunc
+	errorHandler       ErrorHandler
+	wsCheckOrigin      func(r *http.Request) bool
+	pool               sync.Pool
+
+	// 优雅关闭
+	beforeShutdown []func()
+	afterShutdown  []func()
+	shutdownHooks  []func(ctx context.Context)
+	shutdownOnce   sync.Once
+	shutdownErr    error
+	closing        chan struct{}
+	done           chan struct{}
 }
 
 func NewHttpService() *HttpService {
@@ -28,7 +53,10 @@ func NewHttpService() *HttpService {
 		accessLogger: log.NewLogger(),
 		server:       new(http.Server),
 		opts:         newOptions(config.DefaultHttpConfigFile),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
 	}
+	service.errorHandler = defaultErrorHandler
 	service.router = newRouter(service)
 	service.pool.New = func() interface{} {
 		return newContext(service)
@@ -65,6 +93,8 @@ func (service *HttpService) initLog() {
 
 		service.accessLogger = logger
 	}
+
+	service.accessLogFormatter = newAccessLogFormatter(service.opts.AccessLogFormat, service.opts.AccessLogFields)
 }
 
 // 通过配置文件初始化
@@ -77,9 +107,10 @@ func (s *HttpService) Log() *log.Logger {
 }
 
 // Run http server
+// 收到 SIGINT 或 SIGTERM 信号后优雅关闭，处理完进行中的请求再返回
 func (service *HttpService) Run() error {
 	service.setServerOpts()
-	return service.server.ListenAndServe()
+	return service.serve(service.server.Serve)
 }
 
 // Run https server
@@ -89,7 +120,112 @@ func (service *HttpService) RunTLS() error {
 	}
 
 	service.setServerOpts()
-	return service.server.ListenAndServeTLS(service.opts.HttpsCertFile, service.opts.HttpsKeyFile)
+	return service.serve(func(ln net.Listener) error {
+		return service.server.ServeTLS(ln, service.opts.HttpsCertFile, service.opts.HttpsKeyFile)
+	})
+}
+
+func (service *HttpService) serve(fn func(ln net.Listener) error) error {
+	ln, err := service.listen()
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn(ln)
+	}()
+
+	// 平滑重启时通知父进程开始关闭
+	service.notifyReady()
+
+	signals := []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	if service.opts.GracefulRestart && restartSignal != nil {
+		signals = append(signals, restartSignal)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case err := <-errCh:
+			if err != http.ErrServerClosed {
+				return err
+			}
+			// 由 Shutdown 或 Stop 触发，等待关闭流程结束
+			<-service.done
+			return service.shutdownErr
+		case sig := <-sigCh:
+			if sig == restartSignal {
+				pid, err := service.restart()
+				if err != nil {
+					service.logger.Error("restart:", err)
+					continue
+				}
+				service.logger.Info("started new process, pid:", pid, ", shutting down")
+			} else {
+				service.logger.Info("received signal:", sig.String(), ", shutting down")
+			}
+			return service.shutdownWithTimeout()
+		}
+	}
+}
+
+func (service *HttpService) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), service.opts.GetShutdownTimeout())
+	defer cancel()
+
+	return service.Shutdown(ctx)
+}
+
+// BeforeShutdown 注册关闭前执行的函数，此时服务仍在处理请求
+func (service *HttpService) BeforeShutdown(fn func()) {
+	service.beforeShutdown = append(service.beforeShutdown, fn)
+}
+
+// AfterShutdown 注册所有请求处理完成后执行的函数
+func (service *HttpService) AfterShutdown(fn func()) {
+	service.afterShutdown = append(service.afterShutdown, fn)
+}
+
+// Shutdown 优雅关闭服务：停止接收新连接，等待进行中的请求处理完成
+// ctx 超时后强制关闭剩余连接，多次调用只会执行一次
+func (service *HttpService) Shutdown(ctx context.Context) error {
+	service.shutdownOnce.Do(func() {
+		defer close(service.done)
+
+		for _, fn := range service.beforeShutdown {
+			fn()
+		}
+		for _, fn := range service.shutdownHooks {
+			fn(ctx)
+		}
+
+		// 通知长连接（如 SSE）结束，否则 server.Shutdown 会一直等待
+		close(service.closing)
+
+		if err := service.server.Shutdown(ctx); err != nil {
+			service.logger.Error("shutdown:", err)
+			service.shutdownErr = err
+			_ = service.server.Close()
+		}
+
+		for _, fn := range service.afterShutdown {
+			fn()
+		}
+
+		if err := service.accessLogger.Flush(); err != nil {
+			service.logger.Error("flush access log:", err)
+		}
+		_ = service.logger.Flush()
+		// 写完其他异步 Logger 中的日志，进程随后可能退出
+		log.FlushAll()
+	})
+
+	<-service.done
+	return service.shutdownErr
 }
 
 func (service *HttpService) setServerOpts() {
@@ -101,27 +237,27 @@ func (service *HttpService) setServerOpts() {
 
 // ------------
 // 类型 bisinessServer模式
-func (s *HttpService) AddLogic(prefix string, logic Logic) {
-	t := reflect.TypeOf(logic)
-	v := reflect.ValueOf(logic)
-	for i := 0; i < t.NumMethod(); i++ {
-		funcName := t.Method(i).Name
-		if "Init" == funcName {
-			continue
-		}
-
-		s.Any(prefix+"/"+funcName, []HandleFunc{s.wrapLogic(v.Method(i))}...)
-	}
+// AddLogic 注册 Logic 路由，路由规则见 Logic
+func (s *HttpService) AddLogic(prefix string, logic Logic, middlewares ...HandleFunc) {
+	s.addLogic(s.add, prefix, logic, middlewares)
 }
 
+// 支持 func(*Context) 和 func(*Context) error 两种方法签名
 func (s *HttpService) wrapLogic(v reflect.Value) HandleFunc {
 	return func(c *Context) {
-		v.Call([]reflect.Value{reflect.ValueOf(c)})
+		out := v.Call([]reflect.Value{reflect.ValueOf(c)})
+		if len(out) == 1 {
+			if err, ok := out[0].Interface().(error); ok && err != nil {
+				c.AbortWithError(err)
+				return
+			}
+		}
 		c.Next()
 	}
 }
 
 // 传统方式
+// HEAD 和 OPTIONS 请求会自动处理，一般不需要单独注册
 func (s *HttpService) Any(pattern string, h ...HandleFunc) {
 	s.add(http.MethodGet, pattern, h)
 	s.add(http.MethodPost, pattern, h)
@@ -145,6 +281,18 @@ func (s *HttpService) Delete(pattern string, h ...HandleFunc) {
 	s.add(http.MethodDelete, pattern, h)
 }
 
+func (s *HttpService) Patch(pattern string, h ...HandleFunc) {
+	s.add(http.MethodPatch, pattern, h)
+}
+
+func (s *HttpService) Head(pattern string, h ...HandleFunc) {
+	s.add(http.MethodHead, pattern, h)
+}
+
+func (s *HttpService) Options(pattern string, h ...HandleFunc) {
+	s.add(http.MethodOptions, pattern, h)
+}
+
 func (s *HttpService) add(method string, pattern string, handles []HandleFunc) {
 	wrapHandles := make([]HandleFunc, 0, len(handles))
 	for _, h := range handles {
@@ -162,13 +310,25 @@ func (s *HttpService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c.handleHTTPRequest()
 }
 
+// Stop 按配置的超时时间优雅关闭服务
 func (s *HttpService) Stop() error {
-	return s.server.Close()
+	return s.shutdownWithTimeout()
 }
 
 // DI
 
-// USE
+// Use 注册全局中间件，按注册顺序在路由处理函数之前执行
+// 中间件中调用 c.Break() 可以中断后续处理
+func (s *HttpService) Use(middlewares ...HandleFunc) {
+	for _, h := range middlewares {
+		s.middlewares = append(s.middlewares, WrapHandlerFunc(h))
+	}
+}
+
+// Group 创建路由分组，分组内的路由共享前缀和中间件
+func (s *HttpService) Group(prefix string, middlewares ...HandleFunc) *RouterGroup {
+	return newRouterGroup(s, prefix, middlewares)
+}
 
 // ------------
 // 私有方法
@@ -176,7 +336,32 @@ func (s *HttpService) Stop() error {
 
 func catchHandles(code int) HandleFunc {
 	return func(c *Context) {
-		c.String(code, HttpStatus[code])
+		c.AbortWithError(errs.NewWithCode(errs.ErrorCode(code), HttpStatus[code]))
+	}
+}
+
+func (s *HttpService) handleError(c *Context, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("error handler panic:", r)
+		}
+	}()
+
+	s.errorHandler(c, err)
+}
+
+func combineHandles(a, b []HandleFunc) []HandleFunc {
+	handles := make([]HandleFunc, 0, len(a)+len(b))
+	handles = append(handles, a...)
+	handles = append(handles, b...)
+	return handles
+}
+
+// 自动响应 OPTIONS 请求
+func optionsHandle(allow string) HandleFunc {
+	return func(c *Context) {
+		c.SetHeader("Allow", allow)
+		c.resp.WriteHeader(http.StatusNoContent)
 		c.Break()
 	}
 }
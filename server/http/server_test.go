@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"io"
+	nethttp "net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 与 Run 相同的启动流程，但不等待信号，返回监听地址
+func startTestServer(t *testing.T, s *HttpService) string {
+	t.Helper()
+
+	s.setServerOpts()
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(ln)
+
+	return "http://" + ln.Addr().String()
+}
+
+func TestShutdownWaitsInFlight(t *testing.T) {
+	s := NewHttpService()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var (
+		mu       sync.Mutex
+		order    []string
+		finished bool
+	)
+	record := func(step string) {
+		mu.Lock()
+		order = append(order, step)
+		mu.Unlock()
+	}
+
+	s.Get("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String(nethttp.StatusOK, "done")
+		mu.Lock()
+		finished = true
+		mu.Unlock()
+	})
+	s.BeforeShutdown(func() { record("before1") })
+	s.BeforeShutdown(func() { record("before2") })
+	s.AfterShutdown(func() {
+		mu.Lock()
+		if !finished {
+			t.Error("after shutdown hook ran before in-flight request finished")
+		}
+		mu.Unlock()
+		record("after1")
+	})
+	s.AfterShutdown(func() { record("after2") })
+
+	base := startTestServer(t, s)
+
+	type result struct {
+		body string
+		err  error
+	}
+	respCh := make(chan result, 1)
+	go func() {
+		resp, err := nethttp.Get(base + "/slow")
+		if err != nil {
+			respCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		respCh <- result{string(b), err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdownCh := make(chan error, 1)
+	go func() {
+		shutdownCh <- s.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdownCh:
+		t.Fatalf("Shutdown returned before in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-shutdownCh; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	res := <-respCh
+	if res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request = %q, %v; want done", res.body, res.err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"before1", "before2", "after1", "after2"}
+	if len(order) != len(want) {
+		t.Fatalf("hooks = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("hooks = %v, want %v", order, want)
+		}
+	}
+
+	if _, err := nethttp.Get(base + "/slow"); err == nil {
+		t.Error("request after Shutdown succeeded")
+	}
+
+	// 重复调用直接返回第一次的结果
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s := NewHttpService()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s.Get("/hang", func(c *Context) {
+		close(started)
+		<-release
+	})
+	afterCalled := false
+	s.AfterShutdown(func() { afterCalled = true })
+
+	base := startTestServer(t, s)
+	go func() {
+		resp, err := nethttp.Get(base + "/hang")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !afterCalled {
+		t.Error("after shutdown hook not called on timeout")
+	}
+}
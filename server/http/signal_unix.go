@@ -0,0 +1,11 @@
+//go:build !windows
+
+package http
+
+import (
+	"os"
+	"syscall"
+)
+
+// 平滑重启信号
+var restartSignal os.Signal = syscall.SIGUSR2
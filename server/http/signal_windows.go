@@ -0,0 +1,10 @@
+//go:build windows
+
+package http
+
+import (
+	"os"
+)
+
+// Windows 不支持平滑重启
+var restartSignal os.Signal
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSSEClosed = errors.New("sse: stream closed")
+
+// SSEEvent Server-Sent Events 事件
+type SSEEvent struct {
+	ID    string
+	Event string
+	// string 和 []byte 原样输出，其他类型编码为 JSON
+	Data interface{}
+	// 客户端断线重连的等待时间
+	Retry time.Duration
+}
+
+// SSEStream Server-Sent Events 输出流，写方法可以并发调用
+// 客户端断开、服务关闭或处理函数返回后，流被关闭，写入返回 ErrSSEClosed
+type SSEStream struct {
+	c *Context
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// SSE 设置 event-stream 响应头并返回输出流
+//
+//	stream, err := c.SSE()
+//	if err != nil { ... }
+//	stream.Heartbeat(15 * time.Second)
+//	for {
+//		select {
+//		case <-stream.Done():
+//			return
+//		case msg := <-messages:
+//			stream.Send(http.SSEEvent{Event: "message", Data: msg})
+//		}
+//	}
+func (c *Context) SSE() (*SSEStream, error) {
+	if c.sse != nil {
+		return c.sse, nil
+	}
+
+	if _, ok := c.writer.ResponseWriter.(http.Flusher); !ok {
+		return nil, errors.New("sse: response writer does not support flushing")
+	}
+
+	header := c.resp.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 nginx 缓冲
+	header.Set("X-Accel-Buffering", "no")
+	c.resp.WriteHeader(http.StatusOK)
+	c.writer.Flush()
+
+	stream := &SSEStream{
+		c:    c,
+		done: make(chan struct{}),
+		stop: make(chan struct{}),
+	}
+	go stream.watch()
+
+	c.sse = stream
+	return stream, nil
+}
+
+// 客户端重连时携带的最后一个事件 ID
+func (c *Context) LastEventID() string {
+	return c.GetHeader("Last-Event-ID")
+}
+
+func (c *Context) closeSSE() {
+	if c.sse != nil {
+		c.sse.Close()
+	}
+}
+
+func (s *SSEStream) watch() {
+	select {
+	case <-s.c.req.Context().Done():
+	case <-s.c.httpServer.closing:
+	case <-s.stop:
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+}
+
+// Done 流关闭时返回
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// LastEventID 客户端重连时携带的最后一个事件 ID
+func (s *SSEStream) LastEventID() string {
+	return s.c.LastEventID()
+}
+
+// Send 发送事件
+func (s *SSEStream) Send(event SSEEvent) error {
+	var buf bytes.Buffer
+
+	if event.ID != "" {
+		buf.WriteString("id: " + singleLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		buf.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(event.Retry/time.Millisecond), 10) + "\n")
+	}
+
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	if event.Data != nil {
+		for _, line := range strings.Split(sseNewline.Replace(data), "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+	buf.WriteString("\n")
+
+	return s.write(buf.Bytes())
+}
+
+// SendData 发送只包含 data 的事件
+func (s *SSEStream) SendData(data interface{}) error {
+	return s.Send(SSEEvent{Data: data})
+}
+
+// Retry 设置客户端重连等待时间
+func (s *SSEStream) Retry(d time.Duration) error {
+	return s.write([]byte("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n"))
+}
+
+// Heartbeat 按间隔发送注释行，防止代理断开空闲连接，流关闭后自动停止
+func (s *SSEStream) Heartbeat(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.write([]byte(": ping\n\n")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close 关闭流，处理函数返回时会自动调用
+func (s *SSEStream) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+func (s *SSEStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSSEClosed
+	}
+
+	if _, err := s.c.resp.Write(b); err != nil {
+		return err
+	}
+	s.c.writer.Flush()
+
+	return nil
+}
+
+// "\r\n"、"\r" 和 "\n" 都是行结束符，统一为 "\n" 后再按行拆分
+var sseNewline = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSESend(t *testing.T) {
+	s := NewHttpService()
+	s.Get("/events", func(c *Context) {
+		stream, err := c.SSE()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = stream.Send(SSEEvent{ID: "1\n2", Event: "multi\r\nline", Data: "a\nb"})
+		_ = stream.SendData("crlf\r\nline")
+		_ = stream.SendData("lone\rcr\rend")
+		_ = stream.SendData("trailing\n")
+		_ = stream.SendData([]byte("bytes"))
+		_ = stream.SendData(map[string]int{"n": 1})
+		_ = stream.Send(SSEEvent{Data: "", Retry: 1500 * time.Millisecond})
+		_ = stream.Retry(3 * time.Second)
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodGet, "/events", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	want := "id: 12\nevent: multiline\ndata: a\ndata: b\n\n" +
+		"data: crlf\ndata: line\n\n" +
+		"data: lone\ndata: cr\ndata: end\n\n" +
+		"data: trailing\ndata: \n\n" +
+		"data: bytes\n\n" +
+		"data: {\"n\":1}\n\n" +
+		"retry: 1500\ndata: \n\n" +
+		"retry: 3000\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSSEClosedAfterHandlerReturns(t *testing.T) {
+	s := NewHttpService()
+	var stream *SSEStream
+	s.Get("/events", func(c *Context) {
+		stream, _ = c.SSE()
+	})
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(nethttp.MethodGet, "/events", nil))
+
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("stream not closed after handler returned")
+	}
+	if err := stream.SendData("late"); err != ErrSSEClosed {
+		t.Errorf("SendData after close = %v, want %v", err, ErrSSEClosed)
+	}
+}
+
+func TestSSEClientDisconnect(t *testing.T) {
+	s := NewHttpService()
+	done := make(chan error, 1)
+	s.Get("/events", func(c *Context) {
+		stream, _ := c.SSE()
+		<-stream.Done()
+		done <- stream.SendData("late")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(nethttp.MethodGet, "/events", nil).WithContext(ctx)
+	go s.ServeHTTP(httptest.NewRecorder(), r)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != ErrSSEClosed {
+			t.Errorf("SendData after disconnect = %v, want %v", err, ErrSSEClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed after client disconnected")
+	}
+}
+
+func TestSSEStopsOnShutdown(t *testing.T) {
+	s := NewHttpService()
+	stopped := make(chan struct{})
+	s.Get("/events", func(c *Context) {
+		stream, _ := c.SSE()
+		stream.Heartbeat(10 * time.Millisecond)
+		_ = stream.SendData("ready")
+		<-stream.Done()
+		close(stopped)
+	})
+
+	base := startTestServer(t, s)
+	resp, err := nethttp.Get(base + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	br := bufio.NewReader(resp.Body)
+	if line, _ := br.ReadString('\n'); line != "data: ready\n" {
+		t.Fatalf("first line = %q", line)
+	}
+
+	// 流不结束时 server.Shutdown 会一直等待到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %v", elapsed)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("handler still running after Shutdown")
+	}
+
+	rest, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("read after shutdown: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(rest)), "\n") {
+		if line != "" && line != ": ping" {
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"strings"
+)
+
+// 路由参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+type Params []Param
+
+// 按名称获取参数值
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// 路由树节点，按 "/" 分段存储
+// 匹配优先级：静态段 > 命名参数(:name) > 通配符(*name)
+type node struct {
+	// 静态子节点，key 为小写后的路径段
+	children map[string]*node
+
+	// 命名参数子节点，如 "/users/:id"
+	param     *node
+	paramName string
+
+	// 通配符子节点，只能出现在最后一段，如 "/static/*filepath"
+	wildcard     *node
+	wildcardName string
+
+	pattern string
+	handles []HandleFunc
+}
+
+func newNode() *node {
+	return &node{
+		children: make(map[string]*node),
+	}
+}
+
+func (n *node) addRoute(pattern string, handles []HandleFunc) {
+	cur := n
+	segments := splitPath(pattern)
+
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+			panic("http: empty path segment in route '" + pattern + "'")
+
+		case seg[0] == ':':
+			name := seg[1:]
+			if name == "" {
+				panic("http: param name must not be empty in route '" + pattern + "'")
+			}
+			if cur.param == nil {
+				cur.param = newNode()
+				cur.paramName = name
+			} else if cur.paramName != name {
+				panic("http: param ':" + name + "' conflicts with ':" + cur.paramName + "' in route '" + pattern + "'")
+			}
+			cur = cur.param
+
+		case seg[0] == '*':
+			name := seg[1:]
+			if name == "" {
+				panic("http: wildcard name must not be empty in route '" + pattern + "'")
+			}
+			if i != len(segments)-1 {
+				panic("http: wildcard must be the last segment in route '" + pattern + "'")
+			}
+			if cur.wildcard == nil {
+				cur.wildcard = newNode()
+				cur.wildcardName = name
+			} else if cur.wildcardName != name {
+				panic("http: wildcard '*" + name + "' conflicts with '*" + cur.wildcardName + "' in route '" + pattern + "'")
+			}
+			cur = cur.wildcard
+
+		default:
+			seg = strings.ToLower(seg)
+			child, ok := cur.children[seg]
+			if !ok {
+				child = newNode()
+				cur.children[seg] = child
+			}
+			cur = child
+		}
+	}
+
+	if cur.handles != nil {
+		panic("http: route '" + pattern + "' already registered")
+	}
+
+	cur.pattern = pattern
+	cur.handles = handles
+}
+
+// 查找路由，匹配失败时返回 nil
+func (n *node) getRoute(path string) (*node, Params) {
+	var params Params
+	if leaf := n.match(splitPath(path), &params); leaf != nil {
+		return leaf, params
+	}
+	return nil, nil
+}
+
+func (n *node) match(segments []string, params *Params) *node {
+	if len(segments) == 0 {
+		if n.handles != nil {
+			return n
+		}
+		// "/static/*filepath" 同样匹配 "/static"
+		if n.wildcard != nil && n.wildcard.handles != nil {
+			*params = append(*params, Param{Key: n.wildcardName, Value: ""})
+			return n.wildcard
+		}
+		return nil
+	}
+
+	seg, rest := segments[0], segments[1:]
+
+	if child, ok := n.children[strings.ToLower(seg)]; ok {
+		if leaf := child.match(rest, params); leaf != nil {
+			return leaf
+		}
+	}
+
+	if n.param != nil && seg != "" {
+		mark := len(*params)
+		*params = append(*params, Param{Key: n.paramName, Value: seg})
+		if leaf := n.param.match(rest, params); leaf != nil {
+			return leaf
+		}
+		*params = (*params)[:mark]
+	}
+
+	if n.wildcard != nil && n.wildcard.handles != nil {
+		*params = append(*params, Param{Key: n.wildcardName, Value: strings.Join(segments, "/")})
+		return n.wildcard
+	}
+
+	return nil
+}
+
+// "/" 返回空切片，"/a/b" 返回 ["a", "b"]，"/a/" 返回 ["a", ""]
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestTree(patterns ...string) *node {
+	root := newNode()
+	for _, pattern := range patterns {
+		root.addRoute(pattern, []HandleFunc{func(c *Context) {}})
+	}
+	return root
+}
+
+func TestTreePrecedence(t *testing.T) {
+	root := newTestTree(
+		"/",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/profile",
+		"/users/*rest",
+		"/a/b/d",
+		"/a/:x/c",
+		"/static/*filepath",
+	)
+
+	tests := []struct {
+		path    string
+		pattern string
+		params  Params
+	}{
+		{"/", "/", nil},
+		{"/users/new", "/users/new", nil},
+		{"/Users/NEW", "/users/new", nil},
+		{"/users/42", "/users/:id", Params{{"id", "42"}}},
+		{"/users/42/profile", "/users/:id/profile", Params{{"id", "42"}}},
+		{"/users/42/posts/1", "/users/*rest", Params{{"rest", "42/posts/1"}}},
+		// 静态段 b 下没有 c，回溯到参数 :x
+		{"/a/b/c", "/a/:x/c", Params{{"x", "b"}}},
+		{"/a/b/d", "/a/b/d", nil},
+		{"/static", "/static/*filepath", Params{{"filepath", ""}}},
+		{"/static/js/app.js", "/static/*filepath", Params{{"filepath", "js/app.js"}}},
+		{"/a/b", "", nil},
+		{"/nothing", "", nil},
+	}
+
+	for _, tt := range tests {
+		leaf, params := root.getRoute(tt.path)
+		if tt.pattern == "" {
+			if leaf != nil {
+				t.Errorf("%s: expected no match, got %s", tt.path, leaf.pattern)
+			}
+			continue
+		}
+		if leaf == nil {
+			t.Errorf("%s: expected %s, got no match", tt.path, tt.pattern)
+			continue
+		}
+		if leaf.pattern != tt.pattern {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.pattern, leaf.pattern)
+		}
+		if len(params) != len(tt.params) {
+			t.Errorf("%s: expected params %v, got %v", tt.path, tt.params, params)
+			continue
+		}
+		for i := range params {
+			if params[i] != tt.params[i] {
+				t.Errorf("%s: expected params %v, got %v", tt.path, tt.params, params)
+			}
+		}
+	}
+}
+
+func TestTreePanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{"duplicate", []string{"/users/:id", "/users/:id"}},
+		{"conflicting param", []string{"/users/:id", "/users/:name/profile"}},
+		{"conflicting wildcard", []string{"/static/*a", "/static/*b"}},
+		{"wildcard not last", []string{"/static/*filepath/x"}},
+		{"empty param", []string{"/users/:"}},
+		{"empty segment", []string{"/users//x"}},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", tt.name)
+				}
+			}()
+			newTestTree(tt.patterns...)
+		}()
+	}
+}
+
+func TestRouterIgnorePathLastSlash(t *testing.T) {
+	for _, ignore := range []bool{false, true} {
+		s := &HttpService{opts: &Options{IgnorePathLastSlash: ignore}}
+		r := newRouter(s)
+		r.Register(nethttp.MethodGet, "/users/", []HandleFunc{func(c *Context) {}})
+
+		if handles, _ := r.Get(nethttp.MethodGet, "/users"); handles == nil {
+			t.Errorf("ignore=%v: /users should match", ignore)
+		}
+
+		handles, _ := r.Get(nethttp.MethodGet, "/users/")
+		if ignore && handles == nil {
+			t.Errorf("ignore=%v: /users/ should match", ignore)
+		}
+		if !ignore && handles != nil {
+			t.Errorf("ignore=%v: /users/ should not match", ignore)
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	s := NewHttpService()
+	s.Get("/users/:id", func(c *Context) { c.OkString("user " + c.Param("id")) })
+	s.Delete("/users/:id", func(c *Context) { c.OkString("deleted") })
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{nethttp.MethodGet, "/users/1", nethttp.StatusOK, ""},
+		{nethttp.MethodHead, "/users/1", nethttp.StatusOK, ""},
+		{nethttp.MethodPost, "/users/1", nethttp.StatusMethodNotAllowed, "GET, HEAD, DELETE, OPTIONS"},
+		{nethttp.MethodOptions, "/users/1", nethttp.StatusNoContent, "GET, HEAD, DELETE, OPTIONS"},
+		{nethttp.MethodGet, "/posts/1", nethttp.StatusNotFound, ""},
+		{nethttp.MethodPost, "/posts/1", nethttp.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if w.Code != tt.code {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.code, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tt.method, tt.path, tt.allow, allow)
+		}
+	}
+}
+
+func TestRouterNotFoundEnvelope(t *testing.T) {
+	s := NewHttpService()
+	s.Get("/users/:id", func(c *Context) {})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{nethttp.MethodGet, "/posts/1", nethttp.StatusNotFound},
+		{nethttp.MethodPut, "/users/1", nethttp.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		var env Envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+			t.Fatalf("%s %s: body %q: %v", tt.method, tt.path, w.Body.String(), err)
+		}
+		if w.Code != tt.code || int(env.Code) != tt.code || env.Message != HttpStatus[tt.code] {
+			t.Errorf("%s %s: got %d %+v, want %d", tt.method, tt.path, w.Code, env, tt.code)
+		}
+	}
+}
+
+func TestRouterHeadAndOptions(t *testing.T) {
+	s := NewHttpService()
+	s.Get("/users/:id", func(c *Context) {
+		c.SetHeader("X-User", c.Param("id"))
+		c.OkString("user " + c.Param("id"))
+	})
+	s.Get("/files", func(c *Context) { c.OkString("list") })
+	s.Head("/files", func(c *Context) {
+		c.SetHeader("X-Head", "1")
+		c.Writer().WriteHeader(nethttp.StatusOK)
+	})
+	s.Options("/files", func(c *Context) {
+		c.SetHeader("Allow", "GET")
+		c.Writer().WriteHeader(nethttp.StatusOK)
+	})
+
+	// 未注册 HEAD 时使用 GET 路由，但不输出 body
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodHead, "/users/1", nil))
+	if w.Code != nethttp.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-User") != "1" {
+		t.Errorf("HEAD /users/1: got %d %q, X-User %q", w.Code, w.Body.String(), w.Header().Get("X-User"))
+	}
+
+	// 注册了 HEAD 和 OPTIONS 时使用注册的处理函数
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodHead, "/files", nil))
+	if w.Code != nethttp.StatusOK || w.Header().Get("X-Head") != "1" {
+		t.Errorf("HEAD /files: got %d, X-Head %q", w.Code, w.Header().Get("X-Head"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodOptions, "/files", nil))
+	if w.Code != nethttp.StatusOK || w.Header().Get("Allow") != "GET" {
+		t.Errorf("OPTIONS /files: got %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// 自动响应的 OPTIONS 没有 body
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodOptions, "/users/1", nil))
+	if w.Code != nethttp.StatusNoContent || w.Body.Len() != 0 || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("OPTIONS /users/1: got %d %q, Allow %q", w.Code, w.Body.String(), w.Header().Get("Allow"))
+	}
+}
+
+func TestRouterAny(t *testing.T) {
+	s := NewHttpService()
+	s.Any("/items", func(c *Context) { c.OkString(c.Request().Method) })
+	s.Group("/api").Any("/items", func(c *Context) { c.OkString(c.Request().Method) })
+
+	for _, path := range []string{"/items", "/api/items"} {
+		for _, method := range []string{nethttp.MethodGet, nethttp.MethodPost, nethttp.MethodPut, nethttp.MethodDelete} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			if w.Code != nethttp.StatusOK || w.Body.String() != method {
+				t.Errorf("%s %s: got %d %q", method, path, w.Code, w.Body.String())
+			}
+		}
+
+		// Any 不包含 PATCH
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(nethttp.MethodPatch, path, nil))
+		if w.Code != nethttp.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD, POST, PUT, DELETE, OPTIONS" {
+			t.Errorf("PATCH %s: got %d, Allow %q", path, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
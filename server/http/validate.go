@@ -0,0 +1,319 @@
+package http
+
+import (
+	"fmt"
+	errs "lib/error"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 每个结构体类型的校验规则只解析一次
+var rulesCache sync.Map // reflect.Type -> *structRules
+
+type structRules struct {
+	fields []fieldRules
+	err    error
+}
+
+type fieldRules struct {
+	index     int
+	name      string
+	anonymous bool
+	rules     []rule
+}
+
+type rule struct {
+	name    string
+	arg     string
+	limit   float64
+	options []string
+	re      *regexp.Regexp
+}
+
+// Validate 按 `validate` 标签校验结构体字段，多个规则以逗号分隔
+//   - required        不能为零值
+//   - min=N / max=N   数值的大小，字符串、切片、map 的长度
+//   - enum=a|b|c      取值必须在列表中
+//   - regexp=PATTERN  字符串需匹配正则，必须是最后一条规则
+//
+// 除 required 外，其他规则对零值不生效
+// 例如 `validate:"required,min=1,max=32,regexp=^[a-z0-9_]+$"`
+// 校验失败返回 *error.Error，Fields() 中包含每个字段的错误信息
+// 标签本身有误（未知规则、无效的数值或正则）时返回普通错误，包含类型和字段名
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	validErr := errs.NewWithCode(errs.CodeBadRequest, "invalid params")
+	if err := validateStruct(rv, "", validErr); err != nil {
+		return err
+	}
+	if len(validErr.Fields()) > 0 {
+		return validErr
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, validErr *errs.Error) error {
+	sr := typeRules(rv.Type())
+	if sr.err != nil {
+		return sr.err
+	}
+
+	for _, f := range sr.fields {
+		fv := rv.Field(f.index)
+
+		name := prefix + f.name
+		if hasFieldError(validErr, name) {
+			continue
+		}
+
+		if msg := validateField(fv, f.rules); msg != "" {
+			validErr.AddField(name, name+" "+msg)
+			continue
+		}
+
+		// 嵌套结构体
+		inner := fv
+		for inner.Kind() == reflect.Ptr && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() == reflect.Struct && inner.Type() != timeType {
+			var err error
+			if f.anonymous {
+				err = validateStruct(inner, prefix, validErr)
+			} else {
+				err = validateStruct(inner, name+".", validErr)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func typeRules(rt reflect.Type) *structRules {
+	if cached, ok := rulesCache.Load(rt); ok {
+		return cached.(*structRules)
+	}
+
+	sr := parseStructRules(rt)
+	cached, _ := rulesCache.LoadOrStore(rt, sr)
+	return cached.(*structRules)
+}
+
+func parseStructRules(rt reflect.Type) *structRules {
+	sr := &structRules{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		f := fieldRules{index: i, name: fieldName(field), anonymous: field.Anonymous}
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := parseRules(tag)
+			if err != nil {
+				sr.err = fmt.Errorf("validate: %s.%s: %v", rt.String(), field.Name, err)
+				return sr
+			}
+			f.rules = rules
+		}
+		sr.fields = append(sr.fields, f)
+	}
+
+	return sr
+}
+
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		var s string
+		if strings.HasPrefix(tag, "regexp=") {
+			s, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			s, tag = tag[:i], tag[i+1:]
+		} else {
+			s, tag = tag, ""
+		}
+
+		r := rule{name: s}
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			r.name, r.arg = s[:i], s[i+1:]
+		}
+
+		switch r.name {
+		case "required":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(r.arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value '%s'", r.name, r.arg)
+			}
+			r.limit = limit
+		case "enum":
+			r.options = strings.Split(r.arg, "|")
+		case "regexp":
+			re, err := regexp.Compile(r.arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp '%s': %v", r.arg, err)
+			}
+			r.re = re
+		default:
+			return nil, fmt.Errorf("unknown rule '%s'", r.name)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func hasFieldError(e *errs.Error, name string) bool {
+	for _, f := range e.Fields() {
+		if f.Field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		if tag := field.Tag.Get(key); tag != "" && tag != "-" {
+			if name := strings.Split(tag, ",")[0]; name != "" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
+
+// 返回空字符串表示校验通过
+func validateField(fv reflect.Value, rules []rule) string {
+	for _, r := range rules {
+		if r.name == "required" {
+			if isZero(fv) {
+				return "is required"
+			}
+			continue
+		}
+
+		// 非 required 的规则对空值不生效
+		if isZero(fv) {
+			return ""
+		}
+
+		val := fv
+		for val.Kind() == reflect.Ptr {
+			val = val.Elem()
+		}
+
+		var msg string
+		switch r.name {
+		case "min", "max":
+			msg = checkRange(val, r)
+		case "enum":
+			msg = checkEnum(val, r.options)
+		case "regexp":
+			msg = checkRegexp(val, r.re)
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+func checkRange(val reflect.Value, r rule) string {
+	var n float64
+	var isLen bool
+	switch val.Kind() {
+	case reflect.String:
+		n, isLen = float64(len([]rune(val.String()))), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, isLen = float64(val.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = val.Float()
+	default:
+		return ""
+	}
+
+	if r.name == "min" && n < r.limit {
+		if isLen {
+			return "length must be at least " + r.arg
+		}
+		return "must be at least " + r.arg
+	}
+	if r.name == "max" && n > r.limit {
+		if isLen {
+			return "length must be at most " + r.arg
+		}
+		return "must be at most " + r.arg
+	}
+
+	return ""
+}
+
+func checkEnum(val reflect.Value, options []string) string {
+	values := []reflect.Value{val}
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		values = values[:0]
+		for i := 0; i < val.Len(); i++ {
+			values = append(values, val.Index(i))
+		}
+	}
+
+	for _, v := range values {
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, opt := range options {
+			if s == opt {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "must be one of [" + strings.Join(options, ", ") + "]"
+		}
+	}
+
+	return ""
+}
+
+func checkRegexp(val reflect.Value, re *regexp.Regexp) string {
+	if val.Kind() != reflect.String {
+		return ""
+	}
+
+	if !re.MatchString(val.String()) {
+		return "has invalid format"
+	}
+
+	return ""
+}
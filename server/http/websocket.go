@@ -0,0 +1,176 @@
+package http
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	errs "lib/error"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebSocket 握手使用的 GUID，见 RFC 6455 1.3
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	defaultWebSocketMaxMessageSize = 1 << 20
+	defaultWebSocketBufferSize     = 4096
+)
+
+// WSHandleFunc WebSocket 处理函数，函数返回后连接被关闭
+type WSHandleFunc func(c *Context, conn *WSConn)
+
+// WebSocket 注册 WebSocket 路由
+// 握手前会先执行全局中间件、分组中间件以及 middlewares，可以在中间件中完成鉴权
+func (s *HttpService) WebSocket(pattern string, h WSHandleFunc, middlewares ...HandleFunc) {
+	s.Get(pattern, combineHandles(middlewares, []HandleFunc{s.upgradeHandle(h)})...)
+}
+
+// SetWebSocketCheckOrigin 设置跨域校验，默认只允许与 Host 相同的 Origin
+func (s *HttpService) SetWebSocketCheckOrigin(fn func(r *http.Request) bool) {
+	s.wsCheckOrigin = fn
+}
+
+// WebSocket 在分组下注册 WebSocket 路由
+func (g *RouterGroup) WebSocket(pattern string, h WSHandleFunc, middlewares ...HandleFunc) {
+	g.Get(pattern, combineHandles(middlewares, []HandleFunc{g.httpServer.upgradeHandle(h)})...)
+}
+
+func (s *HttpService) upgradeHandle(h WSHandleFunc) HandleFunc {
+	return func(c *Context) {
+		conn, err := s.upgrade(c)
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+
+		defer conn.close()
+		h(c, conn)
+		c.Break()
+	}
+}
+
+// 完成握手，见 RFC 6455 4.2
+func (s *HttpService) upgrade(c *Context) (*WSConn, error) {
+	r := c.req
+
+	if r.Method != http.MethodGet {
+		return nil, errs.NewWithCode(errs.CodeMethodNotAllowed, "websocket: request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, errs.NewWithCode(errs.CodeBadRequest, "websocket: 'Connection' header does not contain 'upgrade'")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, errs.NewWithCode(errs.CodeBadRequest, "websocket: 'Upgrade' header does not contain 'websocket'")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return nil, errs.NewWithCode(errs.CodeBadRequest, "websocket: unsupported version")
+	}
+
+	key := r.Header.Get("Sec-Websocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, errs.NewWithCode(errs.CodeBadRequest, "websocket: invalid 'Sec-WebSocket-Key' header")
+	}
+
+	checkOrigin := s.wsCheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, errs.NewWithCode(errs.CodeForbidden, "websocket: origin not allowed")
+	}
+
+	compress := s.opts.WebsocketCompression && offersDeflate(r.Header)
+
+	netConn, brw, err := c.writer.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	// 清除 http.Server 设置的超时
+	_ = netConn.SetDeadline(time.Time{})
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	resp.WriteString("Upgrade: websocket\r\n")
+	resp.WriteString("Connection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if compress {
+		resp.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	resp.WriteString("\r\n")
+
+	if _, err := netConn.Write([]byte(resp.String())); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	c.writer.status = http.StatusSwitchingProtocols
+
+	maxSize := s.opts.WebsocketMaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultWebSocketMaxMessageSize
+	}
+
+	// 复用握手时已缓冲的数据
+	var br *bufio.Reader
+	if brw.Reader.Buffered() > 0 {
+		br = brw.Reader
+	} else {
+		br = bufio.NewReaderSize(netConn, defaultWebSocketBufferSize)
+	}
+
+	conn := newWSConn(netConn, br, r)
+	conn.compress = compress
+	conn.maxMessageSize = maxSize
+	conn.readTimeout = time.Duration(s.opts.WebsocketReadTimeoutMs) * time.Millisecond
+	conn.writeTimeout = time.Duration(s.opts.WebsocketWriteTimeoutMs) * time.Millisecond
+
+	return conn, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, v := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func offersDeflate(header http.Header) bool {
+	for _, v := range header[http.CanonicalHeaderKey("Sec-WebSocket-Extensions")] {
+		for _, ext := range strings.Split(v, ",") {
+			name := strings.TrimSpace(strings.Split(ext, ";")[0])
+			if strings.EqualFold(name, "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 非浏览器客户端通常不带 Origin，直接放行
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
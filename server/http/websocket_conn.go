@@ -0,0 +1,593 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，见 RFC 6455 5.2
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	rsv2Bit  = 1 << 5
+	rsv3Bit  = 1 << 4
+	maskBit  = 1 << 7
+
+	maxControlFramePayloadSize = 125
+
+	// 关闭时发送关闭帧的最长等待时间
+	closeWriteTimeout = time.Second
+)
+
+var (
+	ErrWSMessageTooBig = errors.New("websocket: message too big")
+	ErrWSClosed        = errors.New("websocket: connection closed")
+
+	// permessage-deflate 压缩数据的结尾，见 RFC 7692 7.2.1
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+)
+
+// CloseError 收到对端的关闭帧时由读方法返回
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// 协议错误，关闭连接时以对应的关闭码通知对端
+type wsProtocolError struct {
+	code int
+	text string
+}
+
+func (e *wsProtocolError) Error() string {
+	return "websocket: " + e.text
+}
+
+// WSConn WebSocket 连接
+// 读方法只能在一个 goroutine 中调用，写方法可以并发调用
+type WSConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	req  *http.Request
+
+	compress       bool
+	maxMessageSize int64
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+
+	wmu sync.Mutex
+
+	// 是否已发送关闭帧
+	closeSent bool
+
+	closing     chan struct{}
+	closingOnce sync.Once
+	closeOnce   sync.Once
+
+	pongHandler func(data string)
+}
+
+func newWSConn(conn net.Conn, br *bufio.Reader, req *http.Request) *WSConn {
+	return &WSConn{
+		conn:    conn,
+		br:      br,
+		req:     req,
+		closing: make(chan struct{}),
+	}
+}
+
+// 握手请求
+func (ws *WSConn) Request() *http.Request {
+	return ws.req
+}
+
+func (ws *WSConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// SetReadDeadline 设置读超时的绝对时间，零值表示不超时
+func (ws *WSConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetReadTimeout 每次读取消息前按该时长刷新读超时，0 表示不超时
+func (ws *WSConn) SetReadTimeout(d time.Duration) {
+	ws.readTimeout = d
+}
+
+func (ws *WSConn) SetWriteTimeout(d time.Duration) {
+	ws.writeTimeout = d
+}
+
+// SetMaxMessageSize 单条消息（合并分片、解压后）的最大字节数
+func (ws *WSConn) SetMaxMessageSize(n int64) {
+	ws.maxMessageSize = n
+}
+
+// SetPongHandler 收到 pong 时回调，通常用于刷新读超时
+func (ws *WSConn) SetPongHandler(h func(data string)) {
+	ws.pongHandler = h
+}
+
+// ReadMessage 读取一条完整的消息，自动处理 ping、pong 和 close 控制帧
+// 收到对端关闭帧时返回 *CloseError
+func (ws *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	if ws.readTimeout > 0 {
+		if err := ws.conn.SetReadDeadline(time.Now().Add(ws.readTimeout)); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	messageType, data, err = ws.readMessage()
+	if err != nil {
+		if pe, ok := err.(*wsProtocolError); ok {
+			_ = ws.WriteClose(pe.code, pe.text)
+		}
+		return 0, nil, err
+	}
+
+	return messageType, data, nil
+}
+
+func (ws *WSConn) ReadText() (string, error) {
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		return "", err
+	}
+	if messageType != TextMessage {
+		return "", errors.New("websocket: expected text message")
+	}
+	return string(data), nil
+}
+
+func (ws *WSConn) ReadBinary() ([]byte, error) {
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if messageType != BinaryMessage {
+		return nil, errors.New("websocket: expected binary message")
+	}
+	return data, nil
+}
+
+// ReadJSON 读取一条消息并解析为 JSON，文本和二进制消息均可
+func (ws *WSConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (ws *WSConn) WriteText(text string) error {
+	return ws.WriteMessage(TextMessage, []byte(text))
+}
+
+func (ws *WSConn) WriteBinary(data []byte) error {
+	return ws.WriteMessage(BinaryMessage, data)
+}
+
+func (ws *WSConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.WriteMessage(PingMessage, data)
+}
+
+// WriteMessage 发送一条消息，messageType 为 TextMessage 或 BinaryMessage 时按协商结果压缩
+func (ws *WSConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > maxControlFramePayloadSize {
+			return errors.New("websocket: control frame payload too large")
+		}
+	case CloseMessage:
+		code, text := CloseNormalClosure, ""
+		if len(data) >= 2 {
+			code, text = int(binary.BigEndian.Uint16(data)), string(data[2:])
+		}
+		return ws.WriteClose(code, text)
+	default:
+		return errors.New("websocket: unknown message type " + strconv.Itoa(messageType))
+	}
+
+	compressed := false
+	if ws.compress && (messageType == TextMessage || messageType == BinaryMessage) {
+		var err error
+		if data, err = deflateMessage(data); err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	return ws.writeFrame(messageType, data, compressed)
+}
+
+// WriteClose 发送关闭帧，之后只能读取不能再写入
+func (ws *WSConn) WriteClose(code int, text string) error {
+	if len(text) > maxControlFramePayloadSize-2 {
+		text = text[:maxControlFramePayloadSize-2]
+	}
+
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = make([]byte, 2+len(text))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		copy(payload[2:], text)
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+
+	if ws.closeSent {
+		return nil
+	}
+	ws.closeSent = true
+
+	return ws.writeFrameLocked(CloseMessage, payload, false)
+}
+
+// Close 发送关闭帧并关闭连接，之后的写操作返回 ErrWSClosed
+// 对端不读取数据时，进行中的写操作会一直阻塞并持有写锁，因此先设置写超时使其尽快返回
+func (ws *WSConn) Close(code int, text string) error {
+	ws.closingOnce.Do(func() {
+		close(ws.closing)
+		_ = ws.conn.SetWriteDeadline(time.Now().Add(closeWriteTimeout))
+	})
+
+	err := ws.WriteClose(code, text)
+	if cerr := ws.closeConn(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// 处理函数返回后调用，未发送关闭帧时以 1000 正常关闭
+// 按 RFC 6455 7.1.1 由服务端先关闭 TCP 连接
+func (ws *WSConn) close() {
+	_ = ws.Close(CloseNormalClosure, "")
+}
+
+// 直接关闭 TCP 连接，不需要等待写锁
+func (ws *WSConn) closeConn() error {
+	var err error
+	ws.closeOnce.Do(func() {
+		err = ws.conn.Close()
+	})
+	return err
+}
+
+func (ws *WSConn) writeFrame(opcode int, payload []byte, compressed bool) error {
+	select {
+	case <-ws.closing:
+		return ErrWSClosed
+	default:
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+
+	if ws.closeSent {
+		return ErrWSClosed
+	}
+	select {
+	case <-ws.closing:
+		return ErrWSClosed
+	default:
+	}
+
+	return ws.writeFrameLocked(opcode, payload, compressed)
+}
+
+// 服务端发送的帧不加掩码
+func (ws *WSConn) writeFrameLocked(opcode int, payload []byte, compressed bool) error {
+	header := make([]byte, 2, 10)
+	header[0] = finalBit | byte(opcode)
+	if compressed {
+		header[0] |= rsv1Bit
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 65535:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if ws.writeTimeout > 0 {
+		if err := ws.conn.SetWriteDeadline(time.Now().Add(ws.writeTimeout)); err != nil {
+			return err
+		}
+	}
+
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(ws.conn)
+	return err
+}
+
+type wsFrame struct {
+	fin        bool
+	compressed bool
+	opcode     int
+	payload    []byte
+}
+
+// 读取一条完整消息，控制帧在分片之间同样会被处理
+func (ws *WSConn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		message     bytes.Buffer
+	)
+
+	for {
+		frame, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frame.opcode {
+		case PingMessage:
+			if err := ws.writeFrame(PongMessage, frame.payload, false); err != nil && err != ErrWSClosed {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if ws.pongHandler != nil {
+				ws.pongHandler(string(frame.payload))
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(frame.payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, &wsProtocolError{CloseProtocolError, "expected continuation frame"}
+			}
+			messageType = frame.opcode
+			compressed = frame.compressed
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, &wsProtocolError{CloseProtocolError, "unexpected continuation frame"}
+			}
+		}
+
+		if ws.maxMessageSize > 0 && int64(message.Len()+len(frame.payload)) > ws.maxMessageSize {
+			return 0, nil, &wsProtocolError{CloseMessageTooBig, ErrWSMessageTooBig.Error()}
+		}
+		message.Write(frame.payload)
+
+		if frame.fin {
+			break
+		}
+	}
+
+	data := message.Bytes()
+	if compressed {
+		var err error
+		if data, err = inflateMessage(data, ws.maxMessageSize); err != nil {
+			if err == ErrWSMessageTooBig {
+				return 0, nil, &wsProtocolError{CloseMessageTooBig, err.Error()}
+			}
+			return 0, nil, &wsProtocolError{CloseProtocolError, "invalid compressed data"}
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(data) {
+		return 0, nil, &wsProtocolError{CloseInvalidFramePayloadData, "invalid utf8 payload"}
+	}
+
+	return messageType, data, nil
+}
+
+// 读取单个帧，见 RFC 6455 5.2
+func (ws *WSConn) readFrame() (*wsFrame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.br, header[:]); err != nil {
+		return nil, err
+	}
+
+	frame := &wsFrame{
+		fin:        header[0]&finalBit != 0,
+		compressed: header[0]&rsv1Bit != 0,
+		opcode:     int(header[0] & 0x0f),
+	}
+
+	if header[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return nil, &wsProtocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+	if frame.compressed && (!ws.compress || frame.opcode == continuationFrame || frame.opcode >= CloseMessage) {
+		return nil, &wsProtocolError{CloseProtocolError, "unexpected rsv1 bit"}
+	}
+
+	switch frame.opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !frame.fin {
+			return nil, &wsProtocolError{CloseProtocolError, "fragmented control frame"}
+		}
+	default:
+		return nil, &wsProtocolError{CloseProtocolError, "unknown opcode " + strconv.Itoa(frame.opcode)}
+	}
+
+	// 客户端发送的帧必须加掩码
+	if header[1]&maskBit == 0 {
+		return nil, &wsProtocolError{CloseProtocolError, "frame is not masked"}
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return nil, &wsProtocolError{CloseProtocolError, "invalid payload length"}
+		}
+	}
+
+	if frame.opcode >= CloseMessage && length > maxControlFramePayloadSize {
+		return nil, &wsProtocolError{CloseProtocolError, "control frame payload too large"}
+	}
+	if ws.maxMessageSize > 0 && length > ws.maxMessageSize {
+		return nil, &wsProtocolError{CloseMessageTooBig, ErrWSMessageTooBig.Error()}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return nil, err
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(ws.br, frame.payload); err != nil {
+		return nil, err
+	}
+	for i := range frame.payload {
+		frame.payload[i] ^= mask[i%4]
+	}
+
+	return frame, nil
+}
+
+// 回应关闭帧，见 RFC 6455 5.5.1
+func (ws *WSConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+
+	switch {
+	case len(payload) == 1:
+		return &wsProtocolError{CloseProtocolError, "invalid close payload"}
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return &wsProtocolError{CloseProtocolError, "invalid close code"}
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return &wsProtocolError{CloseInvalidFramePayloadData, "invalid utf8 payload in close frame"}
+		}
+	}
+
+	if closeErr.Code == CloseNoStatusReceived {
+		_ = ws.WriteClose(CloseNormalClosure, "")
+	} else {
+		_ = ws.WriteClose(closeErr.Code, "")
+	}
+
+	return closeErr
+}
+
+func validCloseCode(code int) bool {
+	switch code {
+	case CloseNoStatusReceived, CloseAbnormalClosure, 1015:
+		return false
+	}
+	return (code >= 1000 && code <= 1014) || (code >= 3000 && code <= 4999)
+}
+
+// flate.Writer 内部状态较大，复用以减少广播时的内存分配
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		fw, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return fw
+	},
+}
+
+func deflateMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw := flateWriterPool.Get().(*flate.Writer)
+	fw.Reset(&buf)
+	defer func() {
+		fw.Reset(nil)
+		flateWriterPool.Put(fw)
+	}()
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+func inflateMessage(data []byte, maxSize int64) ([]byte, error) {
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer fr.Close()
+
+	var r io.Reader = fr
+	if maxSize > 0 {
+		r = io.LimitReader(fr, maxSize+1)
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if maxSize > 0 && int64(buf.Len()) > maxSize {
+		return nil, ErrWSMessageTooBig
+	}
+
+	return buf.Bytes(), nil
+}
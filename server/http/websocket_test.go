@@ -0,0 +1,410 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 客户端帧，带掩码
+func clientFrame(opcode byte, fin bool, payload []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= finalBit
+	}
+
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n)|maskBit)
+	case n <= 65535:
+		frame = append(frame, 126|maskBit, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127|maskBit)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func withBits(frame []byte, bits byte) []byte {
+	frame[0] |= bits
+	return frame
+}
+
+func closePayload(code int, text string) []byte {
+	b := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(b, uint16(code))
+	return append(b, text...)
+}
+
+type serverFrame struct {
+	opcode  int
+	payload []byte
+}
+
+// 读取服务端发送的帧，服务端帧不带掩码
+func readServerFrame(br *bufio.Reader) (serverFrame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return serverFrame{}, err
+	}
+
+	n := int(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return serverFrame{}, err
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return serverFrame{}, err
+		}
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return serverFrame{}, err
+	}
+	return serverFrame{opcode: int(header[0] & 0x0f), payload: payload}, nil
+}
+
+// 基于 net.Pipe 的连接，客户端收到的帧写入 frames
+func newPipeWSConn(t *testing.T) (*WSConn, net.Conn, chan serverFrame) {
+	server, client := net.Pipe()
+	ws := newWSConn(server, bufio.NewReader(server), nil)
+	ws.maxMessageSize = defaultWebSocketMaxMessageSize
+
+	frames := make(chan serverFrame, 16)
+	go func() {
+		defer close(frames)
+		br := bufio.NewReader(client)
+		for {
+			f, err := readServerFrame(br)
+			if err != nil {
+				return
+			}
+			frames <- f
+		}
+	}()
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	return ws, client, frames
+}
+
+func writeFrames(client net.Conn, frames ...[]byte) {
+	go func() {
+		for _, f := range frames {
+			if _, err := client.Write(f); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func expectFrame(t *testing.T, frames chan serverFrame, opcode int) serverFrame {
+	select {
+	case f, ok := <-frames:
+		if !ok {
+			t.Fatalf("expected opcode %d, connection closed", opcode)
+		}
+		if f.opcode != opcode {
+			t.Fatalf("expected opcode %d, got %d", opcode, f.opcode)
+		}
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("expected opcode %d, timed out", opcode)
+	}
+	return serverFrame{}
+}
+
+func expectClose(t *testing.T, frames chan serverFrame, code int) {
+	f := expectFrame(t, frames, CloseMessage)
+	if len(f.payload) < 2 {
+		t.Fatalf("expected close code %d, got empty payload", code)
+	}
+	if got := int(binary.BigEndian.Uint16(f.payload)); got != code {
+		t.Fatalf("expected close code %d, got %d", code, got)
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	s := NewHttpService()
+	s.WebSocket("/ws", func(c *Context, conn *WSConn) {
+		text, err := conn.ReadText()
+		if err == nil {
+			_ = conn.WriteText("echo " + text)
+		}
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// RFC 6455 1.3 中的示例
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+
+	br := bufio.NewReader(conn)
+	resp, err := nethttp.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+
+	_, _ = conn.Write(clientFrame(TextMessage, true, []byte("hi")))
+	f, err := readServerFrame(br)
+	if err != nil || f.opcode != TextMessage || string(f.payload) != "echo hi" {
+		t.Fatalf("unexpected frame %+v, %v", f, err)
+	}
+
+	// 处理函数返回后以 1000 关闭
+	f, err = readServerFrame(br)
+	if err != nil || f.opcode != CloseMessage || binary.BigEndian.Uint16(f.payload) != CloseNormalClosure {
+		t.Fatalf("unexpected close frame %+v, %v", f, err)
+	}
+}
+
+func TestWebSocketHandshakeErrors(t *testing.T) {
+	s := NewHttpService()
+	s.WebSocket("/ws", func(c *Context, conn *WSConn) {})
+
+	valid := map[string]string{
+		"Upgrade":               "websocket",
+		"Connection":            "Upgrade",
+		"Sec-WebSocket-Version": "13",
+		"Sec-WebSocket-Key":     "dGhlIHNhbXBsZSBub25jZQ==",
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		code   int
+	}{
+		{"missing upgrade", "Upgrade", "", nethttp.StatusBadRequest},
+		{"missing connection", "Connection", "keep-alive", nethttp.StatusBadRequest},
+		{"bad version", "Sec-WebSocket-Version", "8", nethttp.StatusBadRequest},
+		{"bad key", "Sec-WebSocket-Key", "short", nethttp.StatusBadRequest},
+		{"cross origin", "Origin", "http://evil.com", nethttp.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(nethttp.MethodGet, "/ws", nil)
+		for k, v := range valid {
+			r.Header.Set(k, v)
+		}
+		r.Header.Set(tt.header, tt.value)
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+		}
+	}
+}
+
+func TestWebSocketFragmentation(t *testing.T) {
+	ws, client, frames := newPipeWSConn(t)
+
+	var pong string
+	ws.SetPongHandler(func(data string) { pong = data })
+
+	// 控制帧可以出现在分片之间
+	writeFrames(client,
+		clientFrame(TextMessage, false, []byte("Hel")),
+		clientFrame(PingMessage, true, []byte("p1")),
+		clientFrame(PongMessage, true, []byte("p2")),
+		clientFrame(continuationFrame, false, []byte("lo, ")),
+		clientFrame(continuationFrame, true, []byte("world")),
+	)
+
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != TextMessage || string(data) != "Hello, world" {
+		t.Fatalf("unexpected message %d %q", messageType, data)
+	}
+	if pong != "p2" {
+		t.Fatalf("pong handler not called, got %q", pong)
+	}
+
+	f := expectFrame(t, frames, PongMessage)
+	if string(f.payload) != "p1" {
+		t.Fatalf("expected pong payload p1, got %q", f.payload)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	unmasked := clientFrame(TextMessage, true, []byte("hi"))
+	unmasked[1] &^= maskBit
+	unmasked = append(unmasked[:2], []byte("hi")...)
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"unmasked", [][]byte{unmasked}, CloseProtocolError},
+		{"reserved bits", [][]byte{withBits(clientFrame(TextMessage, true, nil), rsv2Bit)}, CloseProtocolError},
+		{"rsv1 without compression", [][]byte{withBits(clientFrame(TextMessage, true, nil), rsv1Bit)}, CloseProtocolError},
+		{"unknown opcode", [][]byte{clientFrame(3, true, nil)}, CloseProtocolError},
+		{"fragmented ping", [][]byte{clientFrame(PingMessage, false, nil)}, CloseProtocolError},
+		{"large control frame", [][]byte{clientFrame(PingMessage, true, make([]byte, 126))}, CloseProtocolError},
+		{"unexpected continuation", [][]byte{clientFrame(continuationFrame, true, []byte("x"))}, CloseProtocolError},
+		{"expected continuation", [][]byte{
+			clientFrame(TextMessage, false, []byte("a")),
+			clientFrame(TextMessage, true, []byte("b")),
+		}, CloseProtocolError},
+		{"invalid utf8", [][]byte{clientFrame(TextMessage, true, []byte{0xff, 0xfe})}, CloseInvalidFramePayloadData},
+		{"invalid utf8 across fragments", [][]byte{
+			clientFrame(TextMessage, false, []byte{0xe4, 0xbd}),
+			clientFrame(continuationFrame, true, []byte{0x41}),
+		}, CloseInvalidFramePayloadData},
+		{"close payload of one byte", [][]byte{clientFrame(CloseMessage, true, []byte{0x03})}, CloseProtocolError},
+		{"reserved close code", [][]byte{clientFrame(CloseMessage, true, closePayload(CloseNoStatusReceived, ""))}, CloseProtocolError},
+		{"invalid close code", [][]byte{clientFrame(CloseMessage, true, closePayload(2000, ""))}, CloseProtocolError},
+		{"invalid close reason", [][]byte{clientFrame(CloseMessage, true, append(closePayload(CloseNormalClosure, ""), 0xff))}, CloseInvalidFramePayloadData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, client, frames := newPipeWSConn(t)
+			writeFrames(client, tt.frames...)
+
+			if _, _, err := ws.ReadMessage(); err == nil {
+				t.Fatal("expected error")
+			}
+			expectClose(t, frames, tt.code)
+		})
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		code    int
+		echo    int
+	}{
+		{"normal", closePayload(CloseNormalClosure, "bye"), CloseNormalClosure, CloseNormalClosure},
+		{"application code", closePayload(4000, ""), 4000, 4000},
+		{"empty payload", nil, CloseNoStatusReceived, CloseNormalClosure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, client, frames := newPipeWSConn(t)
+			writeFrames(client, clientFrame(CloseMessage, true, tt.payload))
+
+			_, _, err := ws.ReadMessage()
+			closeErr, ok := err.(*CloseError)
+			if !ok {
+				t.Fatalf("expected *CloseError, got %v", err)
+			}
+			if closeErr.Code != tt.code {
+				t.Fatalf("expected code %d, got %d", tt.code, closeErr.Code)
+			}
+			expectClose(t, frames, tt.echo)
+
+			// 发送关闭帧后不能再写入
+			if err := ws.WriteText("x"); err != ErrWSClosed {
+				t.Fatalf("expected ErrWSClosed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebSocketMaxMessageSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+	}{
+		{"single frame", [][]byte{clientFrame(BinaryMessage, true, make([]byte, 5))}},
+		{"fragments", [][]byte{
+			clientFrame(BinaryMessage, false, make([]byte, 3)),
+			clientFrame(continuationFrame, true, make([]byte, 3)),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, client, frames := newPipeWSConn(t)
+			ws.SetMaxMessageSize(4)
+			writeFrames(client, tt.frames...)
+
+			if _, _, err := ws.ReadMessage(); err == nil {
+				t.Fatal("expected error")
+			}
+			expectClose(t, frames, CloseMessageTooBig)
+		})
+	}
+
+	ws, client, _ := newPipeWSConn(t)
+	ws.SetMaxMessageSize(4)
+	writeFrames(client, clientFrame(BinaryMessage, true, make([]byte, 4)))
+	if _, data, err := ws.ReadMessage(); err != nil || len(data) != 4 {
+		t.Fatalf("expected 4 bytes, got %d, %v", len(data), err)
+	}
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("hello websocket "), 64)
+
+	compressed, err := deflateMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(data) {
+		t.Fatalf("expected compressed size < %d, got %d", len(data), len(compressed))
+	}
+
+	inflated, err := inflateMessage(compressed, 0)
+	if err != nil || !bytes.Equal(inflated, data) {
+		t.Fatalf("roundtrip failed: %v", err)
+	}
+
+	if _, err := inflateMessage(compressed, 16); err != ErrWSMessageTooBig {
+		t.Fatalf("expected ErrWSMessageTooBig, got %v", err)
+	}
+
+	// 压缩后的消息通过 rsv1 标记
+	ws, client, _ := newPipeWSConn(t)
+	ws.compress = true
+	writeFrames(client, withBits(clientFrame(TextMessage, true, compressed), rsv1Bit))
+
+	messageType, msg, err := ws.ReadMessage()
+	if err != nil || messageType != TextMessage || !bytes.Equal(msg, data) {
+		t.Fatalf("unexpected message %d, %v", messageType, err)
+	}
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"context"
+	"lib/client/breaker"
+)
+
+// BreakerInterceptor 熔断拦截器，用于客户端，返回的错误按 Breaker.IsFailure 计入统计
+// 熔断时返回 breaker.ErrOpen
+func BreakerInterceptor(b *breaker.Breaker) Interceptor {
+	return func(ctx context.Context, method string, args, reply interface{}, invoker Invoker) error {
+		return b.Do(func() error {
+			return invoker(ctx, method, args, reply)
+		})
+	}
+}
+
+// BulkheadInterceptor 并发限制拦截器，并发数已满时返回 breaker.ErrBulkheadFull
+func BulkheadInterceptor(b *breaker.Bulkhead) Interceptor {
+	return func(ctx context.Context, method string, args, reply interface{}, invoker Invoker) error {
+		release, err := b.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return invoker(ctx, method, args, reply)
+	}
+}
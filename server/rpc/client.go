@@ -0,0 +1,400 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	errs "lib/error"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrClientClosed = errors.New("rpc: client closed")
+	ErrConnClosed   = errors.New("rpc: connection closed")
+)
+
+// Client RPC 客户端，维护到服务端的连接池，每个连接上的请求多路复用
+// 可以并发使用
+//
+//	client := rpc.NewClient("rpc_client.ini")
+//	var sum int
+//	err := client.Call(ctx, "Arith.Add", &Args{A: 1, B: 2}, &sum)
+type Client struct {
+	opts         *ClientOptions
+	codecID      byte
+	codec        Codec
+	interceptors []Interceptor
+
+	slots []*clientSlot
+	next  uint32
+
+	closeOnce sync.Once
+	closing   chan struct{}
+}
+
+// NewClient 从配置文件创建客户端，配置错误时 panic
+func NewClient(file string) *Client {
+	return NewClientWithOptions(newClientOptions(file))
+}
+
+func NewClientWithOptions(opts *ClientOptions) *Client {
+	if opts.Addr == "" {
+		panic("rpc: client addr is empty")
+	}
+
+	name := opts.Codec
+	if name == "" {
+		name = JSONCodec{}.Name()
+	}
+	id, codec, ok := lookupCodec(name)
+	if !ok {
+		panic("rpc: unknown codec '" + name + "'")
+	}
+
+	c := &Client{
+		opts:    opts,
+		codecID: id,
+		codec:   codec,
+		closing: make(chan struct{}),
+	}
+
+	c.slots = make([]*clientSlot, opts.GetPoolSize())
+	for i := range c.slots {
+		c.slots[i] = &clientSlot{client: c}
+	}
+
+	if interval := opts.GetHeartbeatInterval(); interval > 0 {
+		go c.heartbeat(interval)
+	}
+
+	return c
+}
+
+// Use 注册客户端拦截器，按注册顺序执行，需要在 Call 之前调用
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// Call 调用服务端方法，method 为 "服务名.方法名"
+// ctx 没有 deadline 时使用配置的 call_timeout_ms，ctx 取消后通知服务端取消请求
+// 服务端返回的错误为 *errs.Error，错误码与服务端一致
+func (c *Client) Call(ctx context.Context, method string, args, reply interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		if timeout := c.opts.GetCallTimeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	return chainInterceptors(c.interceptors, c.invoke)(ctx, method, args, reply)
+}
+
+func (c *Client) invoke(ctx context.Context, method string, args, reply interface{}) error {
+	select {
+	case <-c.closing:
+		return ErrClientClosed
+	default:
+	}
+
+	body, err := c.codec.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	cc, err := c.pick().get(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &frame{kind: frameRequest, codec: c.codecID, method: method, body: body}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		req.timeout = uint32((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+
+	resp, err := cc.roundTrip(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if resp.code != 0 {
+		return errs.NewWithCode(errs.ErrorCode(resp.code), resp.message)
+	}
+
+	return c.codec.Unmarshal(resp.body, reply)
+}
+
+// 轮询选择连接
+func (c *Client) pick() *clientSlot {
+	n := atomic.AddUint32(&c.next, 1)
+	return c.slots[int(n)%len(c.slots)]
+}
+
+// 定时发送 ping，连续 GetHeartbeatMaxMissed 次没有收到任何帧时断开连接，下次调用时重连
+func (c *Client) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	maxMissed := int32(c.opts.GetHeartbeatMaxMissed())
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			for _, slot := range c.slots {
+				cc := slot.current()
+				if cc == nil || cc.isBroken() {
+					continue
+				}
+				if atomic.AddInt32(&cc.missed, 1) > maxMissed {
+					cc.fail(ErrConnClosed)
+					continue
+				}
+				_ = cc.write(&frame{kind: framePing})
+			}
+		}
+	}
+}
+
+// Close 关闭所有连接，进行中的调用返回 ErrConnClosed
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closing)
+		for _, slot := range c.slots {
+			if cc := slot.current(); cc != nil {
+				cc.fail(ErrClientClosed)
+			}
+		}
+	})
+	return nil
+}
+
+// 连接池中的一个位置，连接断开后下次使用时重新建立
+type clientSlot struct {
+	client *Client
+	mu     sync.Mutex
+	conn   *clientConn
+
+	// 正在建立连接时不为 nil，建立完成后关闭，同一位置的其他调用等待结果
+	dialing chan struct{}
+}
+
+func (s *clientSlot) current() *clientConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn
+}
+
+// 建立连接时不持有锁，避免拨号超时期间阻塞 current 和 Close
+func (s *clientSlot) get(ctx context.Context) (*clientConn, error) {
+	for {
+		s.mu.Lock()
+		if s.conn != nil && !s.conn.isBroken() {
+			cc := s.conn
+			s.mu.Unlock()
+			return cc, nil
+		}
+
+		if wait := s.dialing; wait != nil {
+			s.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		wait := make(chan struct{})
+		s.dialing = wait
+		s.mu.Unlock()
+
+		cc, err := s.dial(ctx)
+
+		s.mu.Lock()
+		s.dialing = nil
+		if err == nil {
+			s.conn = cc
+		}
+		s.mu.Unlock()
+		close(wait)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// 建立连接期间客户端被关闭，Close 可能没有看到这个连接
+		select {
+		case <-s.client.closing:
+			cc.fail(ErrClientClosed)
+			return nil, ErrClientClosed
+		default:
+		}
+
+		return cc, nil
+	}
+}
+
+func (s *clientSlot) dial(ctx context.Context) (*clientConn, error) {
+	opts := s.client.opts
+	dialer := net.Dialer{Timeout: opts.GetDialTimeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClientConn(s.client, conn), nil
+}
+
+type clientConn struct {
+	client *Client
+	conn   net.Conn
+	wmu    sync.Mutex
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[uint64]chan *frame
+	err     error
+	done    chan struct{}
+
+	// 上次收到帧之后发送的心跳数
+	missed int32
+}
+
+func newClientConn(c *Client, conn net.Conn) *clientConn {
+	cc := &clientConn{
+		client:  c,
+		conn:    conn,
+		pending: make(map[uint64]chan *frame),
+		done:    make(chan struct{}),
+	}
+
+	go cc.readLoop()
+
+	return cc
+}
+
+func (cc *clientConn) roundTrip(ctx context.Context, req *frame) (*frame, error) {
+	ch := make(chan *frame, 1)
+
+	cc.mu.Lock()
+	if cc.err != nil {
+		cc.mu.Unlock()
+		return nil, cc.err
+	}
+	cc.seq++
+	req.seq = cc.seq
+	cc.pending[req.seq] = ch
+	cc.mu.Unlock()
+
+	if err := cc.write(req); err != nil {
+		cc.remove(req.seq)
+		if err != ErrFrameTooLarge {
+			cc.fail(err)
+		}
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-cc.done:
+		// 响应可能在连接断开前已经送达
+		select {
+		case resp := <-ch:
+			return resp, nil
+		default:
+		}
+		return nil, cc.err
+	case <-ctx.Done():
+		if cc.remove(req.seq) {
+			_ = cc.write(&frame{kind: frameCancel, seq: req.seq})
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// 返回是否仍在等待响应
+func (cc *clientConn) remove(seq uint64) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	_, ok := cc.pending[seq]
+	delete(cc.pending, seq)
+	return ok
+}
+
+func (cc *clientConn) write(f *frame) error {
+	data, err := f.encode(cc.client.opts.GetMaxPacketSize())
+	if err != nil {
+		return err
+	}
+
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+
+	if timeout := cc.client.opts.GetWriteTimeout(); timeout > 0 {
+		_ = cc.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	_, err = cc.conn.Write(data)
+	return err
+}
+
+func (cc *clientConn) readLoop() {
+	reader := bufio.NewReaderSize(cc.conn, defaultReadBufferSize)
+	for {
+		f, err := readFrame(reader, cc.client.opts.GetMaxPacketSize())
+		if err != nil {
+			cc.fail(err)
+			return
+		}
+		atomic.StoreInt32(&cc.missed, 0)
+
+		if f.kind != frameResponse {
+			continue
+		}
+
+		cc.mu.Lock()
+		ch, ok := cc.pending[f.seq]
+		delete(cc.pending, f.seq)
+		cc.mu.Unlock()
+
+		if ok {
+			ch <- f
+		}
+	}
+}
+
+// 连接出错后关闭连接，等待中的调用全部返回错误
+func (cc *clientConn) fail(err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.err != nil {
+		return
+	}
+	if err != ErrClientClosed {
+		err = ErrConnClosed
+	}
+	cc.err = err
+	cc.pending = make(map[uint64]chan *frame)
+	close(cc.done)
+
+	_ = cc.conn.Close()
+}
+
+func (cc *clientConn) isBroken() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return cc.err != nil
+}
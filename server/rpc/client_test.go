@@ -0,0 +1,225 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"lib/config/proto"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCallCancel(t *testing.T) {
+	arith, _, client := startArith(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		var reply int
+		done <- client.Call(ctx, "Arith.Wait", &Args{A: 5000}, &reply)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+
+	// 取消帧通知服务端取消请求
+	select {
+	case r := <-arith.waited:
+		if r.err != context.Canceled {
+			t.Errorf("server ctx err = %v, want %v", r.err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server call not canceled")
+	}
+
+	// 连接继续可用
+	var reply int
+	if err := client.Call(context.Background(), "Arith.Add", &Args{1, 2}, &reply); err != nil || reply != 3 {
+		t.Errorf("after cancel: got %d, %v", reply, err)
+	}
+}
+
+func TestCallReconnect(t *testing.T) {
+	_, _, client := startArith(t)
+
+	var reply int
+	if err := client.Call(context.Background(), "Arith.Add", &Args{1, 2}, &reply); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连接断开后下次调用重新建立
+	client.slots[0].current().fail(ErrConnClosed)
+	if err := client.Call(context.Background(), "Arith.Add", &Args{2, 3}, &reply); err != nil || reply != 5 {
+		t.Errorf("after reconnect: got %d, %v", reply, err)
+	}
+
+	_ = client.Close()
+	if err := client.Call(context.Background(), "Arith.Add", &Args{}, &reply); err != ErrClientClosed {
+		t.Errorf("after Close: got %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestSlotDialDoesNotHoldLock(t *testing.T) {
+	_, s, client := startArith(t)
+	slot := client.slots[0]
+
+	// 模拟一个进行中的拨号
+	wait := make(chan struct{})
+	slot.mu.Lock()
+	slot.dialing = wait
+	slot.mu.Unlock()
+
+	// 拨号期间 current 不会被阻塞
+	done := make(chan struct{})
+	go func() {
+		_ = slot.current()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("current blocked by dial")
+	}
+
+	// 其他调用等待拨号结果，并遵守自己的 ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := slot.get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("waiting get = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	got := make(chan *clientConn, 1)
+	go func() {
+		cc, err := slot.get(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		got <- cc
+	}()
+
+	// 拨号完成，等待的调用使用新建立的连接
+	cc, err := slot.dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot.mu.Lock()
+	slot.conn = cc
+	slot.dialing = nil
+	slot.mu.Unlock()
+	close(wait)
+
+	select {
+	case c := <-got:
+		if c != cc {
+			t.Error("waiting get did not reuse the dialed connection")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting get not woken")
+	}
+
+	// 拨号失败时返回错误，不影响之后的调用
+	_ = s.listener.Close()
+	failing := newTestClient(t, s.listener.Addr().String(), func(cfg *proto.RpcClientConfig) {
+		cfg.DialTimeoutMs = 200
+	})
+	var reply int
+	if err := failing.Call(context.Background(), "Arith.Add", &Args{}, &reply); err == nil {
+		t.Error("expected dial error")
+	}
+	failing.slots[0].mu.Lock()
+	defer failing.slots[0].mu.Unlock()
+	if failing.slots[0].dialing != nil {
+		t.Error("dialing not reset after failure")
+	}
+}
+
+// 只接收不响应的服务端，模拟对端失去响应
+func silentServer(t *testing.T) (string, chan struct{}) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			if _, err := readFrame(r, 0); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	return ln.Addr().String(), closed
+}
+
+func TestHeartbeatMissedPongs(t *testing.T) {
+	addr, closed := silentServer(t)
+	client := newTestClient(t, addr, func(cfg *proto.RpcClientConfig) {
+		cfg.HeartbeatIntervalMs = 20
+		cfg.HeartbeatMaxMissed = 2
+	})
+
+	cc, err := client.slots[0].get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第 3 次心跳时已有 2 次没有响应，断开连接
+	select {
+	case <-cc.done:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after missed pongs")
+	}
+	if cc.err != ErrConnClosed {
+		t.Errorf("conn err = %v, want %v", cc.err, ErrConnClosed)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("server side not closed")
+	}
+}
+
+func TestHeartbeatKeepsAlive(t *testing.T) {
+	arith := new(Arith)
+	s := NewRpcService()
+	s.opts.IdleTimeoutMs = 100
+	s.Register(arith)
+	addr := startTestServer(t, s)
+
+	client := newTestClient(t, addr, func(cfg *proto.RpcClientConfig) {
+		cfg.HeartbeatIntervalMs = 20
+		cfg.HeartbeatMaxMissed = 2
+	})
+	var reply int
+	if err := client.Call(context.Background(), "Arith.Add", &Args{1, 1}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	cc := client.slots[0].current()
+
+	// 心跳有响应时连接保持，服务端空闲超时也不会触发
+	time.Sleep(300 * time.Millisecond)
+	if cc.isBroken() {
+		t.Fatal("connection closed although pongs were received")
+	}
+	if err := client.Call(context.Background(), "Arith.Add", &Args{2, 2}, &reply); err != nil || reply != 4 {
+		t.Errorf("got %d, %v", reply, err)
+	}
+	if client.slots[0].current() != cc {
+		t.Error("connection was re-dialed")
+	}
+}
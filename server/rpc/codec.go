@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"encoding/json"
+	"lib/protobuf"
+	"sync"
+)
+
+// Codec 请求参数和响应的序列化方式
+// 服务端按请求帧中的编码 ID 选择 Codec，同一服务可以同时接受不同编码的客户端
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+const (
+	CodecJSON  byte = 1
+	CodecProto byte = 2
+)
+
+var (
+	codecMu     sync.RWMutex
+	codecs      = make(map[byte]Codec)
+	codecByName = make(map[string]byte)
+)
+
+func init() {
+	RegisterCodec(CodecJSON, JSONCodec{})
+	// 参数和响应需要是 protogen 生成的消息
+	RegisterCodec(CodecProto, protobuf.Codec{})
+}
+
+// RegisterCodec 注册编码，id 写入请求帧，客户端和服务端需要一致
+func RegisterCodec(id byte, codec Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	if _, ok := codecs[id]; ok {
+		panic("rpc: codec id already registered")
+	}
+	codecs[id] = codec
+	codecByName[codec.Name()] = id
+}
+
+func getCodec(id byte) (Codec, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	codec, ok := codecs[id]
+	return codec, ok
+}
+
+func lookupCodec(name string) (byte, Codec, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	id, ok := codecByName[name]
+	if !ok {
+		return 0, nil, false
+	}
+	return id, codecs[id], true
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
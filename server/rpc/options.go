@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"time"
+)
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+type Options struct {
+	proto.RpcConfig
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.RpcConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if opts.Addr == "" {
+		opts.Addr = ":9100"
+	}
+}
+
+// 连接空闲超时，超过该时间没有收到任何帧（包括心跳）则断开，0 表示不超时
+func (opt *Options) GetIdleTimeout() time.Duration {
+	return time.Duration(opt.IdleTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetWriteTimeout() time.Duration {
+	return time.Duration(opt.WriteTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetShutdownTimeout() time.Duration {
+	if opt.ShutdownTimeoutMs <= 0 {
+		return time.Duration(30) * time.Second
+	}
+	return time.Duration(opt.ShutdownTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetMaxPacketSize() int {
+	if opt.MaxPacketSize <= 0 {
+		return defaultMaxFrameSize
+	}
+	return opt.MaxPacketSize
+}
+
+// ClientOptions 客户端配置
+type ClientOptions struct {
+	proto.RpcClientConfig
+}
+
+func newClientOptions(file string) *ClientOptions {
+	opts := new(ClientOptions)
+	if err := config.Resolve(file, &opts.RpcClientConfig); err != nil {
+		panic(err)
+	}
+
+	return opts
+}
+
+// 每个服务地址保持的连接数，连接上的请求是多路复用的，默认 2 个
+func (opt *ClientOptions) GetPoolSize() int {
+	if opt.PoolSize <= 0 {
+		return 2
+	}
+	return opt.PoolSize
+}
+
+func (opt *ClientOptions) GetDialTimeout() time.Duration {
+	if opt.DialTimeoutMs <= 0 {
+		return time.Duration(3) * time.Second
+	}
+	return time.Duration(opt.DialTimeoutMs) * time.Millisecond
+}
+
+// 调用方未设置 deadline 时使用的超时时间，0 表示不超时
+func (opt *ClientOptions) GetCallTimeout() time.Duration {
+	return time.Duration(opt.CallTimeoutMs) * time.Millisecond
+}
+
+func (opt *ClientOptions) GetWriteTimeout() time.Duration {
+	return time.Duration(opt.WriteTimeoutMs) * time.Millisecond
+}
+
+// 心跳间隔，应小于服务端的空闲超时，0 表示不发送
+func (opt *ClientOptions) GetHeartbeatInterval() time.Duration {
+	return time.Duration(opt.HeartbeatIntervalMs) * time.Millisecond
+}
+
+// 连续多少次心跳没有收到任何响应后断开连接，默认 3 次
+func (opt *ClientOptions) GetHeartbeatMaxMissed() int {
+	if opt.HeartbeatMaxMissed <= 0 {
+		return 3
+	}
+	return opt.HeartbeatMaxMissed
+}
+
+func (opt *ClientOptions) GetMaxPacketSize() int {
+	if opt.MaxPacketSize <= 0 {
+		return defaultMaxFrameSize
+	}
+	return opt.MaxPacketSize
+}
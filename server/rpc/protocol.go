@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var ErrFrameTooLarge = errors.New("rpc: frame too large")
+
+// 帧类型
+const (
+	frameRequest  = 1
+	frameResponse = 2
+	frameCancel   = 3
+	framePing     = 4
+	framePong     = 5
+)
+
+// 帧格式，多字节字段均为大端序，length 不包含自身：
+//
+//	| length(4) | kind(1) | codec(1) | seq(8) | timeout(4) | code(4) | methodLen(2) | messageLen(2) | method | message | body |
+//
+// 同一连接上的请求通过 seq 区分，响应可以乱序返回
+// timeout 为请求剩余的超时时间（毫秒），0 表示不超时
+// code 和 message 为响应的错误码和错误信息，code 为 0 表示成功
+const frameHeaderSize = 22
+
+const defaultMaxFrameSize = 4 << 20
+
+type frame struct {
+	kind    byte
+	codec   byte
+	seq     uint64
+	timeout uint32
+	code    int32
+	method  string
+	message string
+	body    []byte
+}
+
+func readFrame(r *bufio.Reader, maxSize int) (*frame, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(lenBuf[:]))
+	if length < frameHeaderSize {
+		return nil, errors.New("rpc: invalid frame length")
+	}
+	if maxSize > 0 && length > maxSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	f := &frame{
+		kind:    buf[0],
+		codec:   buf[1],
+		seq:     binary.BigEndian.Uint64(buf[2:]),
+		timeout: binary.BigEndian.Uint32(buf[10:]),
+		code:    int32(binary.BigEndian.Uint32(buf[14:])),
+	}
+
+	methodLen := int(binary.BigEndian.Uint16(buf[18:]))
+	messageLen := int(binary.BigEndian.Uint16(buf[20:]))
+	if frameHeaderSize+methodLen+messageLen > length {
+		return nil, errors.New("rpc: invalid frame header")
+	}
+
+	rest := buf[frameHeaderSize:]
+	f.method = string(rest[:methodLen])
+	f.message = string(rest[methodLen : methodLen+messageLen])
+	f.body = rest[methodLen+messageLen:]
+
+	return f, nil
+}
+
+func (f *frame) encode(maxSize int) ([]byte, error) {
+	method, message := f.method, f.message
+	if len(method) > 0xffff {
+		return nil, errors.New("rpc: method name too long")
+	}
+	if len(message) > 0xffff {
+		message = message[:0xffff]
+	}
+
+	length := frameHeaderSize + len(method) + len(message) + len(f.body)
+	if maxSize > 0 && length > maxSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	buf := make([]byte, 4+length)
+	binary.BigEndian.PutUint32(buf, uint32(length))
+	buf[4] = f.kind
+	buf[5] = f.codec
+	binary.BigEndian.PutUint64(buf[6:], f.seq)
+	binary.BigEndian.PutUint32(buf[14:], f.timeout)
+	binary.BigEndian.PutUint32(buf[18:], uint32(f.code))
+	binary.BigEndian.PutUint16(buf[22:], uint16(len(method)))
+	binary.BigEndian.PutUint16(buf[24:], uint16(len(message)))
+
+	n := 4 + frameHeaderSize
+	n += copy(buf[n:], method)
+	n += copy(buf[n:], message)
+	copy(buf[n:], f.body)
+
+	return buf, nil
+}
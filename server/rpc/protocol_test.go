@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readFrameBytes(b []byte, maxSize int) (*frame, error) {
+	return readFrame(bufio.NewReader(bytes.NewReader(b)), maxSize)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	frames := []*frame{
+		{kind: frameRequest, codec: 1, seq: 1, timeout: 1500, method: "Arith.Add", body: []byte(`{"a":1}`)},
+		{kind: frameResponse, codec: 1, seq: 1<<63 + 5, code: -1, message: "failed"},
+		{kind: frameResponse, codec: 2, seq: 7, code: 404, message: "not found", body: []byte("x")},
+		{kind: framePing},
+		{kind: frameCancel, seq: 9},
+	}
+
+	var stream []byte
+	for _, f := range frames {
+		b, err := f.encode(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := int(binary.BigEndian.Uint32(b)); got != len(b)-4 {
+			t.Errorf("length field = %d, want %d", got, len(b)-4)
+		}
+		stream = append(stream, b...)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(stream))
+	for _, want := range frames {
+		got, err := readFrame(r, defaultMaxFrameSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.kind != want.kind || got.codec != want.codec || got.seq != want.seq || got.timeout != want.timeout ||
+			got.code != want.code || got.method != want.method || got.message != want.message || !bytes.Equal(got.body, want.body) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	if _, err := readFrame(r, defaultMaxFrameSize); err != io.EOF {
+		t.Errorf("after last frame: got %v, want EOF", err)
+	}
+}
+
+func TestFrameMalformed(t *testing.T) {
+	valid, _ := (&frame{kind: frameRequest, method: "A.B", body: []byte("body")}).encode(0)
+
+	withLength := func(n uint32) []byte {
+		b := append([]byte(nil), valid...)
+		binary.BigEndian.PutUint32(b, n)
+		return b
+	}
+	// methodLen 超出帧长度
+	badMethodLen := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint16(badMethodLen[4+18:], 100)
+	// methodLen + messageLen 超出帧长度
+	badMessageLen := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint16(badMessageLen[4+20:], 0xffff)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "EOF"},
+		{"partial length", valid[:3], "unexpected EOF"},
+		{"length below header", withLength(frameHeaderSize - 1), "invalid frame length"},
+		{"zero length", withLength(0), "invalid frame length"},
+		{"truncated body", valid[:len(valid)-1], "unexpected EOF"},
+		{"header only", valid[:4], "EOF"},
+		{"method length overflow", badMethodLen, "invalid frame header"},
+		{"message length overflow", badMessageLen, "invalid frame header"},
+	}
+
+	for _, tt := range tests {
+		_, err := readFrameBytes(tt.data, defaultMaxFrameSize)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestFrameOversized(t *testing.T) {
+	f := &frame{kind: frameRequest, method: "A.B", body: make([]byte, 100)}
+	b, err := f.encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.encode(len(b) - 5); err != ErrFrameTooLarge {
+		t.Errorf("encode over max: got %v, want %v", err, ErrFrameTooLarge)
+	}
+	if _, err := f.encode(len(b) - 4); err != nil {
+		t.Errorf("encode at max: got %v", err)
+	}
+
+	if _, err := readFrameBytes(b, len(b)-5); err != ErrFrameTooLarge {
+		t.Errorf("read over max: got %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	// 长度字段声明 4GB，不会按该长度分配内存
+	huge := []byte{0xff, 0xff, 0xff, 0xff}
+	if _, err := readFrameBytes(huge, defaultMaxFrameSize); err != ErrFrameTooLarge {
+		t.Errorf("read huge length: got %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	if _, err := (&frame{method: strings.Repeat("m", 0x10000)}).encode(0); err == nil {
+		t.Error("expected error for method name too long")
+	}
+
+	// 过长的错误信息被截断
+	long, err := (&frame{kind: frameResponse, message: strings.Repeat("e", 0x10001)}).encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readFrameBytes(long, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.message) != 0xffff {
+		t.Errorf("message length = %d, want %d", len(got.message), 0xffff)
+	}
+}
@@ -0,0 +1,511 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"lib/config"
+	"lib/core"
+	errs "lib/error"
+	"lib/log"
+	"net"
+	"os"
+	"os/signal"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultReadBufferSize = 4096
+
+// RpcService 基于 TCP 的 RPC 服务
+// 服务通过反射注册，方法名为 "服务名.方法名"，同一连接上的请求并发处理
+//
+//	type Arith struct{}
+//
+//	func (a *Arith) Add(ctx context.Context, args *Args, reply *int) error {
+//		*reply = args.A + args.B
+//		return nil
+//	}
+//
+//	s := rpc.NewRpcService()
+//	s.Register(new(Arith))
+//	s.Run()
+type RpcService struct {
+	core.App
+
+	opts         *Options
+	services     map[string]*service
+	interceptors []Interceptor
+	listener     net.Listener
+	logger       *log.Logger
+	accessLogger *log.Logger
+
+	mu    sync.Mutex
+	conns map[*serverConn]struct{}
+	wg    sync.WaitGroup
+
+	// 优雅关闭
+	shutdownOnce sync.Once
+	shutdownErr  error
+	closing      chan struct{}
+	done         chan struct{}
+}
+
+func NewRpcService() *RpcService {
+	service := &RpcService{
+		logger:       log.NewLogger(),
+		accessLogger: log.NewLogger(),
+		opts:         newOptions(config.DefaultRpcConfigFile),
+		services:     make(map[string]*service),
+		conns:        make(map[*serverConn]struct{}),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	service.init()
+
+	return service
+}
+
+// 通过 Init 方法初始化
+func (s *RpcService) init() {
+	s.App.Init()
+	s.initLog()
+}
+
+func (s *RpcService) initLog() {
+	if s.opts.AccessLog {
+		logger := log.NewLogger()
+
+		if s.opts.AccessLogDir != "" {
+			logger.SetOutputDir(s.opts.AccessLogDir)
+			logger.SetOutputByName("access.log")
+
+			switch s.opts.AccessLogRotate {
+			case "D", "d", "day", "daily":
+				logger.SetRotateDaily()
+			case "H", "h", "hour", "hourly":
+				logger.SetRotateHourly()
+			default:
+				logger.SetRotateHourly()
+			}
+		}
+
+		s.accessLogger = logger
+	}
+}
+
+func (s *RpcService) Log() *log.Logger {
+	return s.logger
+}
+
+// Register 以类型名作为服务名注册，需要在 Run 之前调用
+// 实现了 Init() 方法时注册前会先调用
+func (s *RpcService) Register(rcvr interface{}) {
+	s.RegisterName("", rcvr)
+}
+
+// RegisterName 以指定的服务名注册
+func (s *RpcService) RegisterName(name string, rcvr interface{}) {
+	if i, ok := rcvr.(interface{ Init() }); ok {
+		i.Init()
+	}
+
+	svc := newService(name, rcvr)
+	if _, ok := s.services[svc.name]; ok {
+		panic("rpc: service '" + svc.name + "' already registered")
+	}
+	s.services[svc.name] = svc
+}
+
+// Use 注册服务端拦截器，按注册顺序执行
+func (s *RpcService) Use(interceptors ...Interceptor) {
+	s.interceptors = append(s.interceptors, interceptors...)
+}
+
+// Run rpc server
+// 收到 SIGINT 或 SIGTERM 信号后优雅关闭，处理完进行中的请求再返回
+func (s *RpcService) Run() error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.serve(ln)
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return err
+		}
+		// 由 Shutdown 或 Stop 触发，等待关闭流程结束
+		<-s.done
+		return s.shutdownErr
+	case sig := <-sigCh:
+		s.logger.Info("received signal:", sig.String(), ", shutting down")
+		return s.Stop()
+	}
+}
+
+func (s *RpcService) serve(ln net.Listener) error {
+	var delay time.Duration
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.closing:
+				return nil
+			default:
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				s.logger.Warn("accept:", err, ", retrying in", delay)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		sc := newServerConn(s, conn)
+		if !s.addConn(sc) {
+			_ = conn.Close()
+			continue
+		}
+
+		go sc.serve()
+	}
+}
+
+func (s *RpcService) addConn(sc *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closing:
+		return false
+	default:
+	}
+
+	if max := s.opts.MaxConnections; max > 0 && len(s.conns) >= max {
+		s.logger.Warn("too many connections, reject:", sc.conn.RemoteAddr().String())
+		return false
+	}
+
+	s.conns[sc] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *RpcService) removeConn(sc *serverConn) {
+	s.mu.Lock()
+	delete(s.conns, sc)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
+
+// 执行一次调用，返回响应帧
+func (s *RpcService) call(ctx context.Context, req *frame) *frame {
+	resp := &frame{kind: frameResponse, codec: req.codec, seq: req.seq}
+
+	err := s.invoke(ctx, req, resp)
+	if err != nil {
+		var e *errs.Error
+		if errors.As(err, &e) && e.Code() != errs.CodeOK {
+			resp.code = int32(e.Code())
+		} else {
+			resp.code = int32(errs.CodeInternal)
+		}
+		resp.message = err.Error()
+		resp.body = nil
+	}
+
+	return resp
+}
+
+func (s *RpcService) invoke(ctx context.Context, req *frame, resp *frame) (err error) {
+	codec, ok := getCodec(req.codec)
+	if !ok {
+		return errs.NewWithCode(errs.CodeBadRequest, "rpc: unknown codec")
+	}
+
+	serviceName, methodName, ok := splitMethod(req.method)
+	if !ok {
+		return errs.NewWithCode(errs.CodeBadRequest, "rpc: invalid method '"+req.method+"'")
+	}
+	svc, ok := s.services[serviceName]
+	if !ok {
+		return errs.NewWithCode(errs.CodeNotFound, "rpc: service '"+serviceName+"' not found")
+	}
+	mt, ok := svc.methods[methodName]
+	if !ok {
+		return errs.NewWithCode(errs.CodeNotFound, "rpc: method '"+req.method+"' not found")
+	}
+
+	argv := mt.newArgs()
+	if err := codec.Unmarshal(req.body, argv.Interface()); err != nil {
+		return errs.WrapWithCode(errs.CodeBadRequest, err)
+	}
+	replyv := reflect.New(mt.replyType.Elem())
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic recovered, method:", req.method, ":", r, "\n", string(debug.Stack()))
+			err = errs.NewWithCode(errs.CodeInternal, "rpc: internal error")
+		}
+	}()
+
+	invoker := func(ctx context.Context, method string, args, reply interface{}) error {
+		return mt.call(ctx, reflect.ValueOf(args), reflect.ValueOf(reply))
+	}
+	if err := chainInterceptors(s.interceptors, invoker)(ctx, req.method, argv.Interface(), replyv.Interface()); err != nil {
+		return err
+	}
+
+	resp.body, err = codec.Marshal(replyv.Interface())
+	return err
+}
+
+// Shutdown 优雅关闭服务：停止接收新连接和新请求，等待进行中的请求完成
+// ctx 超时后取消进行中的请求并关闭连接，多次调用只会执行一次
+func (s *RpcService) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		defer close(s.done)
+
+		s.mu.Lock()
+		close(s.closing)
+		conns := make([]*serverConn, 0, len(s.conns))
+		for sc := range s.conns {
+			conns = append(conns, sc)
+		}
+		s.mu.Unlock()
+
+		if s.listener != nil {
+			_ = s.listener.Close()
+		}
+
+		for _, sc := range conns {
+			sc.interrupt()
+		}
+
+		wait := make(chan struct{})
+		go func() {
+			s.wg.Wait()
+			close(wait)
+		}()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			s.logger.Error("shutdown:", ctx.Err())
+			s.shutdownErr = ctx.Err()
+			for _, sc := range conns {
+				sc.close()
+			}
+		}
+
+		if err := s.accessLogger.Flush(); err != nil {
+			s.logger.Error("flush access log:", err)
+		}
+		_ = s.logger.Flush()
+		log.FlushAll()
+	})
+
+	<-s.done
+	return s.shutdownErr
+}
+
+// Stop 按配置的超时时间优雅关闭服务
+func (s *RpcService) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.GetShutdownTimeout())
+	defer cancel()
+
+	return s.Shutdown(ctx)
+}
+
+// 服务端连接
+type serverConn struct {
+	service *RpcService
+	conn    net.Conn
+	reader  *bufio.Reader
+	wmu     sync.Mutex
+
+	// 设置读超时与关闭通知之间的同步
+	deadlineMu sync.Mutex
+
+	// 进行中的请求，收到取消帧或连接断开时取消
+	mu      sync.Mutex
+	pending map[uint64]context.CancelFunc
+	calls   sync.WaitGroup
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+func newServerConn(s *RpcService, conn net.Conn) *serverConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &serverConn{
+		service: s,
+		conn:    conn,
+		reader:  bufio.NewReaderSize(conn, defaultReadBufferSize),
+		pending: make(map[uint64]context.CancelFunc),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (sc *serverConn) serve() {
+	s := sc.service
+	defer s.removeConn(sc)
+	defer sc.close()
+
+	idle := s.opts.GetIdleTimeout()
+	for {
+		if !sc.setReadDeadline(idle) {
+			break
+		}
+
+		f, err := readFrame(sc.reader, s.opts.GetMaxPacketSize())
+		if err != nil {
+			select {
+			case <-s.closing:
+				// 服务关闭，等待进行中的请求完成
+			default:
+				// 连接断开，取消进行中的请求
+				sc.cancel()
+				if err != io.EOF {
+					s.logger.Warn("read from", sc.conn.RemoteAddr().String(), ":", err)
+				}
+			}
+			break
+		}
+
+		switch f.kind {
+		case frameRequest:
+			sc.calls.Add(1)
+			go sc.handle(f)
+		case frameCancel:
+			sc.mu.Lock()
+			if cancel, ok := sc.pending[f.seq]; ok {
+				cancel()
+			}
+			sc.mu.Unlock()
+		case framePing:
+			_ = sc.write(&frame{kind: framePong, seq: f.seq})
+		}
+	}
+
+	sc.calls.Wait()
+}
+
+func (sc *serverConn) handle(req *frame) {
+	defer sc.calls.Done()
+
+	s := sc.service
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(sc.ctx)
+	if req.timeout > 0 {
+		ctx, cancel = context.WithTimeout(sc.ctx, time.Duration(req.timeout)*time.Millisecond)
+	}
+	defer cancel()
+
+	sc.mu.Lock()
+	sc.pending[req.seq] = cancel
+	sc.mu.Unlock()
+	defer func() {
+		sc.mu.Lock()
+		delete(sc.pending, req.seq)
+		sc.mu.Unlock()
+	}()
+
+	resp := s.call(ctx, req)
+
+	if s.opts.AccessLog {
+		s.accessLogger.Printf("%s %s %d %s", sc.conn.RemoteAddr().String(), req.method, resp.code, time.Since(start))
+	}
+
+	// 已取消的请求客户端不再等待响应
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	if err := sc.write(resp); err != nil {
+		if err == ErrFrameTooLarge {
+			resp = &frame{kind: frameResponse, codec: req.codec, seq: req.seq, code: int32(errs.CodeInternal), message: err.Error()}
+			err = sc.write(resp)
+		}
+		if err != nil {
+			s.logger.Warn("write to", sc.conn.RemoteAddr().String(), ":", err)
+		}
+	}
+}
+
+func (sc *serverConn) write(f *frame) error {
+	data, err := f.encode(sc.service.opts.GetMaxPacketSize())
+	if err != nil {
+		return err
+	}
+
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+
+	if timeout := sc.service.opts.GetWriteTimeout(); timeout > 0 {
+		_ = sc.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	_, err = sc.conn.Write(data)
+	return err
+}
+
+// 每次读帧前刷新空闲超时，服务关闭后不再刷新
+func (sc *serverConn) setReadDeadline(idle time.Duration) bool {
+	sc.deadlineMu.Lock()
+	defer sc.deadlineMu.Unlock()
+
+	select {
+	case <-sc.service.closing:
+		return false
+	default:
+	}
+
+	if idle > 0 {
+		_ = sc.conn.SetReadDeadline(time.Now().Add(idle))
+	}
+	return true
+}
+
+// 服务关闭时唤醒阻塞在读上的连接，不影响进行中的请求
+func (sc *serverConn) interrupt() {
+	sc.deadlineMu.Lock()
+	defer sc.deadlineMu.Unlock()
+
+	_ = sc.conn.SetReadDeadline(time.Now())
+}
+
+func (sc *serverConn) close() {
+	sc.closeOnce.Do(func() {
+		sc.cancel()
+		_ = sc.conn.Close()
+	})
+}
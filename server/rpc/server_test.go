@@ -0,0 +1,348 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"lib/config/proto"
+	errs "lib/error"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Args struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type Arith struct {
+	inited bool
+
+	// Wait 返回时 ctx 的状态，用于检查超时和取消是否传递到服务端
+	waited chan waitResult
+}
+
+type waitResult struct {
+	err         error
+	deadline    time.Time
+	hasDeadline bool
+}
+
+func (a *Arith) Init() {
+	a.inited = true
+	a.waited = make(chan waitResult, 8)
+}
+
+func (a *Arith) Add(ctx context.Context, args *Args, reply *int) error {
+	*reply = args.A + args.B
+	return nil
+}
+
+// 非指针参数
+func (a *Arith) Mul(ctx context.Context, args Args, reply *int) error {
+	*reply = args.A * args.B
+	return nil
+}
+
+func (a *Arith) Div(ctx context.Context, args *Args, reply *int) error {
+	if args.B == 0 {
+		return errs.NewWithCode(errs.CodeBadRequest, "divide by zero")
+	}
+	*reply = args.A / args.B
+	return nil
+}
+
+func (a *Arith) Fail(ctx context.Context, args *Args, reply *int) error {
+	return errors.New("plain error")
+}
+
+func (a *Arith) Panic(ctx context.Context, args *Args, reply *int) error {
+	panic("boom")
+}
+
+// 等待 args.A 毫秒或 ctx 结束
+func (a *Arith) Wait(ctx context.Context, args *Args, reply *int) error {
+	select {
+	case <-time.After(time.Duration(args.A) * time.Millisecond):
+		*reply = args.A
+	case <-ctx.Done():
+	}
+	deadline, ok := ctx.Deadline()
+	a.waited <- waitResult{err: ctx.Err(), deadline: deadline, hasDeadline: ok}
+	return ctx.Err()
+}
+
+// 签名不符，不注册
+func (a *Arith) Name() string { return "arith" }
+
+// 在回环地址上启动服务，测试结束时关闭
+func startTestServer(t *testing.T, s *RpcService) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = ln
+	go s.serve(ln)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	})
+
+	return ln.Addr().String()
+}
+
+func newTestClient(t *testing.T, addr string, fn func(cfg *proto.RpcClientConfig)) *Client {
+	t.Helper()
+
+	opts := &ClientOptions{RpcClientConfig: proto.RpcClientConfig{Addr: addr, PoolSize: 1}}
+	if fn != nil {
+		fn(&opts.RpcClientConfig)
+	}
+	c := NewClientWithOptions(opts)
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func startArith(t *testing.T) (*Arith, *RpcService, *Client) {
+	t.Helper()
+
+	arith := new(Arith)
+	s := NewRpcService()
+	s.Register(arith)
+	addr := startTestServer(t, s)
+
+	return arith, s, newTestClient(t, addr, nil)
+}
+
+func TestRegisterAndCall(t *testing.T) {
+	arith, s, client := startArith(t)
+	if !arith.inited {
+		t.Error("Init not called on Register")
+	}
+	if _, ok := s.services["Arith"].methods["Name"]; ok {
+		t.Error("Name should not be registered")
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		method string
+		args   Args
+		want   int
+		code   errs.ErrorCode
+	}{
+		{"Arith.Add", Args{1, 2}, 3, 0},
+		{"Arith.Mul", Args{3, 4}, 12, 0},
+		{"Arith.Div", Args{9, 3}, 3, 0},
+		{"Arith.Div", Args{1, 0}, 0, errs.CodeBadRequest},
+		{"Arith.Fail", Args{}, 0, errs.CodeInternal},
+		{"Arith.Panic", Args{}, 0, errs.CodeInternal},
+		{"Arith.Missing", Args{}, 0, errs.CodeNotFound},
+		{"Missing.Add", Args{}, 0, errs.CodeNotFound},
+		{"NoDot", Args{}, 0, errs.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		var reply int
+		err := client.Call(ctx, tt.method, &tt.args, &reply)
+		if tt.code == 0 {
+			if err != nil || reply != tt.want {
+				t.Errorf("%s: got %d, %v, want %d", tt.method, reply, err, tt.want)
+			}
+			continue
+		}
+
+		var e *errs.Error
+		if !errors.As(err, &e) || e.Code() != tt.code {
+			t.Errorf("%s: got %v, want code %d", tt.method, err, tt.code)
+		}
+	}
+
+	// panic 后服务继续可用
+	var reply int
+	if err := client.Call(ctx, "Arith.Add", &Args{2, 2}, &reply); err != nil || reply != 4 {
+		t.Errorf("after panic: got %d, %v", reply, err)
+	}
+}
+
+type unexported struct{}
+
+func (u *unexported) Add(ctx context.Context, args *Args, reply *int) error { return nil }
+
+type NoMethods struct{}
+
+func (n *NoMethods) Add(args *Args) error { return nil }
+
+func TestRegisterInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(s *RpcService)
+		want string
+	}{
+		{"unexported", func(s *RpcService) { s.Register(new(unexported)) }, "not exported"},
+		{"no methods", func(s *RpcService) { s.Register(new(NoMethods)) }, "no suitable methods"},
+		{"duplicate", func(s *RpcService) {
+			s.Register(new(Arith))
+			s.Register(new(Arith))
+		}, "already registered"},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("%s: panic %q, want %q", tt.name, msg, tt.want)
+				}
+			}()
+			tt.fn(NewRpcService())
+		}()
+	}
+
+	// 指定服务名
+	s := NewRpcService()
+	s.RegisterName("Math", new(Arith))
+	if _, ok := s.services["Math"]; !ok {
+		t.Error("RegisterName did not use the given name")
+	}
+}
+
+func TestCallTimeoutPropagation(t *testing.T) {
+	arith, s, _ := startArith(t)
+	client := newTestClient(t, s.listener.Addr().String(), func(cfg *proto.RpcClientConfig) {
+		cfg.CallTimeoutMs = 100
+	})
+
+	// ctx 没有 deadline 时使用 call_timeout_ms
+	var reply int
+	start := time.Now()
+	err := client.Call(context.Background(), "Arith.Wait", &Args{A: 5000}, &reply)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call took %v", elapsed)
+	}
+
+	// 服务端的 deadline 与客户端相同，先到期的 deadline 或客户端的取消帧结束请求
+	select {
+	case r := <-arith.waited:
+		if r.err == nil {
+			t.Error("server call not stopped")
+		}
+		if !r.hasDeadline || r.deadline.Sub(start) > 200*time.Millisecond {
+			t.Errorf("server deadline = %v, want about 100ms after the call", r.deadline.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server call not stopped by deadline")
+	}
+
+	// 调用方的 deadline 优先，剩余时间传递到服务端
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Call(ctx, "Arith.Wait", &Args{A: 1}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	r := <-arith.waited
+	if !r.hasDeadline {
+		t.Fatal("server ctx has no deadline")
+	}
+	if remaining := time.Until(r.deadline); remaining < time.Second || remaining > 2*time.Second {
+		t.Errorf("server deadline in %v, want about 2s", remaining)
+	}
+}
+
+func TestShutdownDrainsCalls(t *testing.T) {
+	arith, s, client := startArith(t)
+
+	type result struct {
+		reply int
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var reply int
+		err := client.Call(context.Background(), "Arith.Wait", &Args{A: 300}, &reply)
+		done <- result{reply, err}
+	}()
+
+	// 等待请求到达服务端
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		var pending int
+		for sc := range s.conns {
+			sc.mu.Lock()
+			pending += len(sc.pending)
+			sc.mu.Unlock()
+		}
+		s.mu.Unlock()
+		if pending > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("call not received")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	// Shutdown 返回时进行中的请求已经完成并返回响应
+	select {
+	case r := <-done:
+		if r.err != nil || r.reply != 300 {
+			t.Errorf("in-flight call = %d, %v", r.reply, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("in-flight call not completed")
+	}
+	if r := <-arith.waited; r.err != nil {
+		t.Errorf("in-flight call ctx canceled: %v", r.err)
+	}
+
+	// 关闭后不再接受新连接
+	var reply int
+	other := newTestClient(t, s.listener.Addr().String(), nil)
+	if err := other.Call(context.Background(), "Arith.Add", &Args{1, 1}, &reply); err == nil {
+		t.Error("call after shutdown succeeded")
+	}
+}
+
+func TestShutdownTimeoutCancelsCalls(t *testing.T) {
+	arith, s, client := startArith(t)
+
+	done := make(chan error, 1)
+	go func() {
+		var reply int
+		done <- client.Call(context.Background(), "Arith.Wait", &Args{A: 10000}, &reply)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	select {
+	case r := <-arith.waited:
+		if r.err != context.Canceled {
+			t.Errorf("server ctx err = %v, want %v", r.err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server call not canceled after shutdown timeout")
+	}
+	if err := <-done; err != ErrConnClosed {
+		t.Errorf("client got %v, want %v", err, ErrConnClosed)
+	}
+}
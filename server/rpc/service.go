@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Invoker 执行一次调用，服务端为注册的方法，客户端为发送请求
+type Invoker func(ctx context.Context, method string, args, reply interface{}) error
+
+// Interceptor 调用拦截器，可以在 invoker 前后增加鉴权、日志、熔断等逻辑
+// 不调用 invoker 时调用被中断
+type Interceptor func(ctx context.Context, method string, args, reply interface{}, invoker Invoker) error
+
+// 按注册顺序组合拦截器，先注册的在外层
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, args, reply interface{}) error {
+			return interceptor(ctx, method, args, reply, next)
+		}
+	}
+	return invoker
+}
+
+type service struct {
+	name    string
+	rcvr    reflect.Value
+	methods map[string]*methodType
+}
+
+type methodType struct {
+	method    reflect.Value
+	argType   reflect.Type
+	replyType reflect.Type
+}
+
+// 导出方法的签名为 func(ctx context.Context, args *Args, reply *Reply) error 时注册
+// args 也可以是非指针类型
+func newService(name string, rcvr interface{}) *service {
+	v := reflect.ValueOf(rcvr)
+	if name == "" {
+		name = reflect.Indirect(v).Type().Name()
+	}
+	if name == "" || !isExported(name) {
+		panic("rpc: service name '" + name + "' is not exported")
+	}
+
+	s := &service{
+		name:    name,
+		rcvr:    v,
+		methods: make(map[string]*methodType),
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.Name == "Init" {
+			continue
+		}
+		if mt := newMethodType(v.Method(i)); mt != nil {
+			s.methods[m.Name] = mt
+		}
+	}
+
+	if len(s.methods) == 0 {
+		panic("rpc: service '" + name + "' has no suitable methods")
+	}
+
+	return s
+}
+
+func newMethodType(m reflect.Value) *methodType {
+	t := m.Type()
+	if t.NumIn() != 3 || t.NumOut() != 1 {
+		return nil
+	}
+	if t.In(0) != contextType || t.Out(0) != errorType {
+		return nil
+	}
+
+	replyType := t.In(2)
+	if replyType.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	return &methodType{
+		method:    m,
+		argType:   t.In(1),
+		replyType: replyType,
+	}
+}
+
+func (m *methodType) newArgs() reflect.Value {
+	if m.argType.Kind() == reflect.Ptr {
+		return reflect.New(m.argType.Elem())
+	}
+	return reflect.New(m.argType)
+}
+
+// args 为 newArgs 的返回值
+func (m *methodType) call(ctx context.Context, args, reply reflect.Value) error {
+	if m.argType.Kind() != reflect.Ptr {
+		args = args.Elem()
+	}
+
+	out := m.method.Call([]reflect.Value{reflect.ValueOf(ctx), args, reply})
+	if err, ok := out[0].Interface().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// "Arith.Add" => "Arith", "Add"
+func splitMethod(name string) (string, string, bool) {
+	i := strings.LastIndexByte(name, '.')
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,273 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+var ErrPacketTooLarge = errors.New("tcp: packet too large")
+
+// Packet 消息包，按 Cmd 分发到对应的处理函数
+type Packet struct {
+	Cmd  uint32
+	Body []byte
+}
+
+// Codec 消息编解码，负责从字节流中切分出完整的消息包
+// Decode 只会在连接的读 goroutine 中调用
+type Codec interface {
+	Decode(r *bufio.Reader) (*Packet, error)
+	Encode(p *Packet) ([]byte, error)
+}
+
+// HeaderCodec 固定长度包头 + 包体
+// 包头中包含包体长度，可选包含命令号，如：
+//
+//	| length(4) | cmd(4) | body(length) |
+type HeaderCodec struct {
+	// 包头长度
+	HeaderSize int
+
+	// 长度字段在包头中的偏移量和字节数，字节数支持 1、2、4、8
+	LengthOffset int
+	LengthSize   int
+
+	// 命令号字段在包头中的偏移量和字节数，字节数为 0 时不解析命令号
+	CmdOffset int
+	CmdSize   int
+
+	// 长度字段是否包含包头长度
+	LengthIncludesHeader bool
+
+	// 默认大端序
+	ByteOrder binary.ByteOrder
+
+	// 包体最大字节数，0 表示使用默认值 4MB，最大不超过 math.MaxInt32
+	MaxBodySize int
+}
+
+// NewLengthPrefixCodec 4 字节长度 + 4 字节命令号 + 包体，大端序
+func NewLengthPrefixCodec(maxBodySize int) *HeaderCodec {
+	return &HeaderCodec{
+		HeaderSize:   8,
+		LengthOffset: 0,
+		LengthSize:   4,
+		CmdOffset:    4,
+		CmdSize:      4,
+		ByteOrder:    binary.BigEndian,
+		MaxBodySize:  maxBodySize,
+	}
+}
+
+// 校验字段位置，字段需要在包头范围内
+func (c *HeaderCodec) validate() error {
+	if c.HeaderSize <= 0 {
+		return errors.New("tcp: invalid header size")
+	}
+	if !validFieldSize(c.LengthSize) {
+		return errors.New("tcp: length size must be 1, 2, 4 or 8")
+	}
+	if c.LengthOffset < 0 || c.LengthOffset+c.LengthSize > c.HeaderSize {
+		return errors.New("tcp: length field out of header")
+	}
+	if c.CmdSize != 0 {
+		if !validFieldSize(c.CmdSize) {
+			return errors.New("tcp: cmd size must be 0, 1, 2, 4 or 8")
+		}
+		if c.CmdOffset < 0 || c.CmdOffset+c.CmdSize > c.HeaderSize {
+			return errors.New("tcp: cmd field out of header")
+		}
+	}
+	return nil
+}
+
+func validFieldSize(size int) bool {
+	return size == 1 || size == 2 || size == 4 || size == 8
+}
+
+func (c *HeaderCodec) maxBodySize() uint64 {
+	if c.MaxBodySize <= 0 {
+		return defaultMaxPacketSize
+	}
+	if c.MaxBodySize > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return uint64(c.MaxBodySize)
+}
+
+func (c *HeaderCodec) byteOrder() binary.ByteOrder {
+	if c.ByteOrder == nil {
+		return binary.BigEndian
+	}
+	return c.ByteOrder
+}
+
+func (c *HeaderCodec) Decode(r *bufio.Reader) (*Packet, error) {
+	header := make([]byte, c.HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length, err := getUint(c.byteOrder(), header[c.LengthOffset:], c.LengthSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.LengthIncludesHeader {
+		if length < uint64(c.HeaderSize) {
+			return nil, errors.New("tcp: invalid packet length")
+		}
+		length -= uint64(c.HeaderSize)
+	}
+	// 长度来自对端，分配内存前必须检查
+	if length > c.maxBodySize() {
+		return nil, ErrPacketTooLarge
+	}
+
+	p := new(Packet)
+	if c.CmdSize > 0 {
+		cmd, err := getUint(c.byteOrder(), header[c.CmdOffset:], c.CmdSize)
+		if err != nil {
+			return nil, err
+		}
+		p.Cmd = uint32(cmd)
+	}
+
+	p.Body = make([]byte, length)
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (c *HeaderCodec) Encode(p *Packet) ([]byte, error) {
+	if uint64(len(p.Body)) > c.maxBodySize() {
+		return nil, ErrPacketTooLarge
+	}
+
+	length := uint64(len(p.Body))
+	if c.LengthIncludesHeader {
+		length += uint64(c.HeaderSize)
+	}
+	// 长度字段放不下时对端会解析出错误的长度
+	if c.LengthSize < 8 && length >= 1<<(8*uint(c.LengthSize)) {
+		return nil, ErrPacketTooLarge
+	}
+
+	buf := make([]byte, c.HeaderSize+len(p.Body))
+	if err := putUint(c.byteOrder(), buf[c.LengthOffset:], c.LengthSize, length); err != nil {
+		return nil, err
+	}
+	if c.CmdSize > 0 {
+		if err := putUint(c.byteOrder(), buf[c.CmdOffset:], c.CmdSize, uint64(p.Cmd)); err != nil {
+			return nil, err
+		}
+	}
+	copy(buf[c.HeaderSize:], p.Body)
+
+	return buf, nil
+}
+
+// DelimiterCodec 以分隔符切分消息，如按行分隔的文本协议
+// 默认命令号为 0，可以通过 Parse 从消息中解析命令号
+type DelimiterCodec struct {
+	Delimiter []byte
+
+	// 单条消息最大字节数，0 表示不限制
+	MaxFrameSize int
+
+	// 解析消息，为空时整条消息作为包体
+	Parse func(frame []byte) (*Packet, error)
+}
+
+// NewLineCodec 按 "\n" 切分，兼容 "\r\n"
+func NewLineCodec(maxFrameSize int) *DelimiterCodec {
+	return &DelimiterCodec{
+		Delimiter:    []byte("\n"),
+		MaxFrameSize: maxFrameSize,
+		Parse: func(frame []byte) (*Packet, error) {
+			return &Packet{Body: bytes.TrimSuffix(frame, []byte("\r"))}, nil
+		},
+	}
+}
+
+func (c *DelimiterCodec) Decode(r *bufio.Reader) (*Packet, error) {
+	if len(c.Delimiter) == 0 {
+		return nil, errors.New("tcp: empty delimiter")
+	}
+
+	last := c.Delimiter[len(c.Delimiter)-1]
+
+	var frame []byte
+	for {
+		chunk, err := r.ReadSlice(last)
+		if c.MaxFrameSize > 0 && len(frame)+len(chunk) > c.MaxFrameSize+len(c.Delimiter) {
+			return nil, ErrPacketTooLarge
+		}
+		frame = append(frame, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasSuffix(frame, c.Delimiter) {
+			break
+		}
+	}
+
+	frame = frame[:len(frame)-len(c.Delimiter)]
+	if c.Parse != nil {
+		return c.Parse(frame)
+	}
+
+	return &Packet{Body: frame}, nil
+}
+
+func (c *DelimiterCodec) Encode(p *Packet) ([]byte, error) {
+	if c.MaxFrameSize > 0 && len(p.Body) > c.MaxFrameSize {
+		return nil, ErrPacketTooLarge
+	}
+
+	buf := make([]byte, 0, len(p.Body)+len(c.Delimiter))
+	buf = append(buf, p.Body...)
+	buf = append(buf, c.Delimiter...)
+
+	return buf, nil
+}
+
+func getUint(order binary.ByteOrder, b []byte, size int) (uint64, error) {
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(order.Uint16(b)), nil
+	case 4:
+		return uint64(order.Uint32(b)), nil
+	case 8:
+		return order.Uint64(b), nil
+	}
+	return 0, errors.New("tcp: unsupported field size")
+}
+
+func putUint(order binary.ByteOrder, b []byte, size int, v uint64) error {
+	switch size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		order.PutUint16(b, uint16(v))
+	case 4:
+		order.PutUint32(b, uint32(v))
+	case 8:
+		order.PutUint64(b, v)
+	default:
+		return errors.New("tcp: unsupported field size")
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func decodeBytes(codec Codec, b []byte) (*Packet, error) {
+	return codec.Decode(bufio.NewReader(bytes.NewReader(b)))
+}
+
+func TestHeaderCodecRoundTrip(t *testing.T) {
+	codecs := map[string]*HeaderCodec{
+		"length prefix": NewLengthPrefixCodec(0),
+		// | cmd(1) | reserved(1) | length(2, 含包头) |
+		"little endian": {
+			HeaderSize: 4, LengthOffset: 2, LengthSize: 2, CmdOffset: 0, CmdSize: 1,
+			LengthIncludesHeader: true, ByteOrder: binary.LittleEndian,
+		},
+		"no cmd":     {HeaderSize: 8, LengthOffset: 0, LengthSize: 8},
+		"1 byte len": {HeaderSize: 2, LengthOffset: 1, LengthSize: 1, CmdOffset: 0, CmdSize: 1},
+	}
+
+	for name, codec := range codecs {
+		if err := codec.validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		var stream []byte
+		packets := []*Packet{{Cmd: 1, Body: []byte("hello")}, {Cmd: 2, Body: []byte{}}, {Cmd: 200, Body: bytes.Repeat([]byte("x"), 250)}}
+		for _, p := range packets {
+			if codec.CmdSize == 0 {
+				p.Cmd = 0
+			}
+			b, err := codec.Encode(p)
+			if err != nil {
+				t.Fatalf("%s: encode: %v", name, err)
+			}
+			stream = append(stream, b...)
+		}
+
+		// 多个包连续写入，逐个解析
+		r := bufio.NewReader(bytes.NewReader(stream))
+		for _, want := range packets {
+			got, err := codec.Decode(r)
+			if err != nil {
+				t.Fatalf("%s: decode: %v", name, err)
+			}
+			if got.Cmd != want.Cmd || !bytes.Equal(got.Body, want.Body) {
+				t.Errorf("%s: got cmd %d body %q, want cmd %d body %q", name, got.Cmd, got.Body, want.Cmd, want.Body)
+			}
+		}
+		if _, err := codec.Decode(r); err != io.EOF {
+			t.Errorf("%s: decode after last packet = %v, want EOF", name, err)
+		}
+	}
+}
+
+func TestHeaderCodecWireFormat(t *testing.T) {
+	b, err := NewLengthPrefixCodec(0).Encode(&Packet{Cmd: 0x0102, Body: []byte("ab")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0, 2, 0, 0, 1, 2, 'a', 'b'}
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded = %v, want %v", b, want)
+	}
+}
+
+func TestHeaderCodecTruncated(t *testing.T) {
+	codec := NewLengthPrefixCodec(0)
+	full, _ := codec.Encode(&Packet{Cmd: 1, Body: []byte("hello")})
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, io.EOF},
+		{"partial header", full[:5], io.ErrUnexpectedEOF},
+		{"header only", full[:8], io.EOF},
+		{"partial body", full[:10], io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodeBytes(codec, tt.data); err != tt.err {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestHeaderCodecOversized(t *testing.T) {
+	header := func(length uint32) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint32(b, length)
+		return b
+	}
+	long := make([]byte, 16)
+	binary.BigEndian.PutUint64(long, 1<<40)
+
+	tests := []struct {
+		name  string
+		codec *HeaderCodec
+		data  []byte
+	}{
+		{"over max body", NewLengthPrefixCodec(10), header(11)},
+		// MaxBodySize 为 0 时使用默认上限，不会按对端给出的长度分配内存
+		{"over default", NewLengthPrefixCodec(0), header(defaultMaxPacketSize + 1)},
+		{"max uint32", NewLengthPrefixCodec(0), header(math.MaxUint32)},
+		{"over int32", &HeaderCodec{HeaderSize: 16, LengthSize: 8, MaxBodySize: math.MaxInt64}, long},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodeBytes(tt.codec, tt.data); err != ErrPacketTooLarge {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrPacketTooLarge)
+		}
+	}
+
+	// 长度字段包含包头时，长度不能小于包头
+	codec := &HeaderCodec{HeaderSize: 8, LengthSize: 4, LengthIncludesHeader: true}
+	if _, err := decodeBytes(codec, header(7)); err == nil || err == ErrPacketTooLarge {
+		t.Errorf("length below header: got %v", err)
+	}
+
+	if _, err := NewLengthPrefixCodec(10).Encode(&Packet{Body: make([]byte, 11)}); err != ErrPacketTooLarge {
+		t.Errorf("encode over max body: got %v", err)
+	}
+	small := &HeaderCodec{HeaderSize: 2, LengthOffset: 1, LengthSize: 1, CmdSize: 1}
+	if _, err := small.Encode(&Packet{Body: make([]byte, 256)}); err != ErrPacketTooLarge {
+		t.Errorf("encode over length field: got %v", err)
+	}
+}
+
+func TestHeaderCodecValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec *HeaderCodec
+		err   string
+	}{
+		{"zero header", &HeaderCodec{LengthSize: 4}, "header size"},
+		{"bad length size", &HeaderCodec{HeaderSize: 8, LengthSize: 3}, "length size"},
+		{"length out of header", &HeaderCodec{HeaderSize: 4, LengthOffset: 2, LengthSize: 4}, "length field"},
+		{"negative offset", &HeaderCodec{HeaderSize: 4, LengthOffset: -1, LengthSize: 4}, "length field"},
+		{"bad cmd size", &HeaderCodec{HeaderSize: 8, LengthSize: 4, CmdOffset: 4, CmdSize: 3}, "cmd size"},
+		{"cmd out of header", &HeaderCodec{HeaderSize: 8, LengthSize: 4, CmdOffset: 6, CmdSize: 4}, "cmd field"},
+	}
+
+	for _, tt := range tests {
+		err := tt.codec.validate()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	s := NewTcpService()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("SetCodec should panic on invalid HeaderCodec")
+			}
+		}()
+		s.SetCodec(&HeaderCodec{HeaderSize: 4, LengthOffset: 2, LengthSize: 4})
+	}()
+}
+
+func TestDelimiterCodec(t *testing.T) {
+	codec := NewLineCodec(8)
+
+	r := bufio.NewReader(strings.NewReader("one\r\ntwo\n\nthree"))
+	for _, want := range []string{"one", "two", ""} {
+		p, err := codec.Decode(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p.Body) != want {
+			t.Errorf("got %q, want %q", p.Body, want)
+		}
+	}
+	// 没有分隔符的残留数据
+	if _, err := codec.Decode(r); err != io.EOF {
+		t.Errorf("incomplete frame: got %v, want EOF", err)
+	}
+
+	if _, err := decodeBytes(codec, []byte("123456789\n")); err != ErrPacketTooLarge {
+		t.Errorf("oversized frame: got %v", err)
+	}
+
+	b, err := codec.Encode(&Packet{Body: []byte("hi")})
+	if err != nil || string(b) != "hi\n" {
+		t.Errorf("encode = %q, %v", b, err)
+	}
+	if _, err := codec.Encode(&Packet{Body: []byte("123456789")}); err != ErrPacketTooLarge {
+		t.Errorf("encode oversized: got %v", err)
+	}
+
+	// 多字节分隔符
+	crlf := &DelimiterCodec{Delimiter: []byte("\r\n")}
+	p, err := decodeBytes(crlf, []byte("a\rb\r\n"))
+	if err != nil || string(p.Body) != "a\rb" {
+		t.Errorf("crlf decode = %v, %v", p, err)
+	}
+}
@@ -0,0 +1,140 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrConnClosed = errors.New("tcp: connection closed")
+
+const defaultReadBufferSize = 4096
+
+// Conn 客户端连接，Send 可以在多个 goroutine 中并发调用
+type Conn struct {
+	ID string
+
+	service *TcpService
+	conn    net.Conn
+	reader  *bufio.Reader
+
+	// 保证一个包完整写入
+	writeMu sync.Mutex
+
+	// 设置读超时与关闭通知之间的同步，见 setReadDeadline
+	deadlineMu sync.Mutex
+
+	attrMu sync.RWMutex
+	attrs  map[string]interface{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(service *TcpService, conn net.Conn) *Conn {
+	return &Conn{
+		ID:      newConnID(),
+		service: service,
+		conn:    conn,
+		reader:  bufio.NewReaderSize(conn, defaultReadBufferSize),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// NetConn 底层连接，直接读写会破坏编解码，仅用于获取连接信息
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// Send 编码并发送消息包，超过 WriteTimeoutMs 未写完返回超时错误
+func (c *Conn) Send(p *Packet) error {
+	select {
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+	}
+
+	data, err := c.service.codec.Encode(p)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if timeout := c.service.opts.GetWriteTimeout(); timeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	_, err = c.conn.Write(data)
+	return err
+}
+
+// Write 发送命令号为 cmd 的消息
+func (c *Conn) Write(cmd uint32, body []byte) error {
+	return c.Send(&Packet{Cmd: cmd, Body: body})
+}
+
+// Close 关闭连接，读 goroutine 随之退出并触发 OnDisconnect
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Set 保存连接级别的数据，如登录后的用户信息
+func (c *Conn) Set(key string, value interface{}) {
+	c.attrMu.Lock()
+	defer c.attrMu.Unlock()
+
+	if c.attrs == nil {
+		c.attrs = make(map[string]interface{})
+	}
+	c.attrs[key] = value
+}
+
+func (c *Conn) Get(key string) (interface{}, bool) {
+	c.attrMu.RLock()
+	defer c.attrMu.RUnlock()
+
+	value, ok := c.attrs[key]
+	return value, ok
+}
+
+// 每次读包前刷新空闲超时，服务关闭后不再刷新
+func (c *Conn) setReadDeadline(idle time.Duration) bool {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	select {
+	case <-c.service.closing:
+		return false
+	default:
+	}
+
+	if idle > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(idle))
+	}
+	return true
+}
+
+// 服务关闭时唤醒阻塞在读上的连接，正在处理的包不受影响
+func (c *Conn) interrupt() {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	_ = c.conn.SetReadDeadline(time.Now())
+}
@@ -0,0 +1,81 @@
+package tcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"lib/protobuf"
+	"time"
+)
+
+type HandleFunc func(c *Context)
+
+// Context 单个消息包的处理上下文
+type Context struct {
+	Conn   *Conn
+	Packet *Packet
+
+	start time.Time
+}
+
+func newContext(conn *Conn, p *Packet) *Context {
+	return &Context{
+		Conn:   conn,
+		Packet: p,
+		start:  time.Now(),
+	}
+}
+
+func (c *Context) Cmd() uint32 {
+	return c.Packet.Cmd
+}
+
+func (c *Context) Body() []byte {
+	return c.Packet.Body
+}
+
+// BindJSON 将包体解析为 JSON
+func (c *Context) BindJSON(v interface{}) error {
+	return json.Unmarshal(c.Packet.Body, v)
+}
+
+// BindProto 将包体解析为 protobuf 消息
+func (c *Context) BindProto(m protobuf.Message) error {
+	return protobuf.Unmarshal(c.Packet.Body, m)
+}
+
+// Reply 向当前连接发送命令号为 cmd 的消息
+func (c *Context) Reply(cmd uint32, body []byte) error {
+	return c.Conn.Write(cmd, body)
+}
+
+func (c *Context) ReplyJSON(cmd uint32, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Conn.Write(cmd, body)
+}
+
+func (c *Context) ReplyProto(cmd uint32, m protobuf.Message) error {
+	body, err := protobuf.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.Conn.Write(cmd, body)
+}
+
+// Set 和 Get 作用于连接，在同一连接的后续消息中仍然有效
+func (c *Context) Set(key string, value interface{}) {
+	c.Conn.Set(key, value)
+}
+
+func (c *Context) Get(key string) (interface{}, bool) {
+	return c.Conn.Get(key)
+}
+
+func newConnID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,54 @@
+package tcp
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"time"
+)
+
+const defaultMaxPacketSize = 4 << 20
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+type Options struct {
+	proto.TcpConfig
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.TcpConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if opts.Addr == "" {
+		opts.Addr = ":9090"
+	}
+}
+
+// 连接空闲超时，超过该时间没有收到任何数据则断开，0 表示不超时
+func (opt *Options) GetIdleTimeout() time.Duration {
+	return time.Duration(opt.IdleTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetWriteTimeout() time.Duration {
+	return time.Duration(opt.WriteTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetShutdownTimeout() time.Duration {
+	if opt.ShutdownTimeoutMs <= 0 {
+		return time.Duration(30) * time.Second
+	}
+	return time.Duration(opt.ShutdownTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetMaxPacketSize() int {
+	if opt.MaxPacketSize <= 0 {
+		return defaultMaxPacketSize
+	}
+	return opt.MaxPacketSize
+}
@@ -0,0 +1,365 @@
+package tcp
+
+import (
+	"context"
+	"io"
+	"lib/config"
+	"lib/core"
+	"lib/log"
+	"net"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TcpService 基于 TCP 长连接的服务
+// 按 Codec 从字节流中切分消息包，再按命令号分发到处理函数
+// 同一连接的消息按顺序处理，不同连接之间并发处理
+//
+//	s := tcp.NewTcpService()
+//	s.Heartbeat(0)
+//	s.Handle(1, func(c *tcp.Context) {
+//		c.Reply(2, c.Body())
+//	})
+//	s.Run()
+type TcpService struct {
+	core.App
+
+	opts         *Options
+	codec        Codec
+	handlers     map[uint32]HandleFunc
+	notFound     HandleFunc
+	onConnect    func(conn *Conn)
+	onDisconnect func(conn *Conn)
+	listener     net.Listener
+	logger       *log.Logger
+	accessLogger *log.Logger
+
+	mu    sync.Mutex
+	conns map[*Conn]struct{}
+	wg    sync.WaitGroup
+
+	// 优雅关闭
+	shutdownOnce sync.Once
+	shutdownErr  error
+	closing      chan struct{}
+	done         chan struct{}
+}
+
+func NewTcpService() *TcpService {
+	service := &TcpService{
+		logger:       log.NewLogger(),
+		accessLogger: log.NewLogger(),
+		opts:         newOptions(config.DefaultTcpConfigFile),
+		handlers:     make(map[uint32]HandleFunc),
+		conns:        make(map[*Conn]struct{}),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	service.codec = NewLengthPrefixCodec(service.opts.GetMaxPacketSize())
+
+	service.init()
+
+	return service
+}
+
+// 通过 Init 方法初始化
+func (service *TcpService) init() {
+	service.App.Init()
+	service.initLog()
+}
+
+func (service *TcpService) initLog() {
+	if service.opts.AccessLog {
+		logger := log.NewLogger()
+
+		if service.opts.AccessLogDir != "" {
+			logger.SetOutputDir(service.opts.AccessLogDir)
+			logger.SetOutputByName("access.log")
+
+			switch service.opts.AccessLogRotate {
+			case "D", "d", "day", "daily":
+				logger.SetRotateDaily()
+			case "H", "h", "hour", "hourly":
+				logger.SetRotateHourly()
+			default:
+				logger.SetRotateHourly()
+			}
+		}
+
+		service.accessLogger = logger
+	}
+}
+
+func (s *TcpService) Log() *log.Logger {
+	return s.logger
+}
+
+// SetCodec 设置编解码，默认为 NewLengthPrefixCodec
+// HeaderCodec 的字段超出包头范围时 panic
+func (s *TcpService) SetCodec(codec Codec) {
+	if hc, ok := codec.(*HeaderCodec); ok {
+		if err := hc.validate(); err != nil {
+			panic(err.Error())
+		}
+	}
+	s.codec = codec
+}
+
+// Handle 注册命令号对应的处理函数，需要在 Run 之前调用
+func (s *TcpService) Handle(cmd uint32, h HandleFunc) {
+	if _, ok := s.handlers[cmd]; ok {
+		panic("tcp: duplicate handler for cmd")
+	}
+	s.handlers[cmd] = h
+}
+
+// NotFound 设置未注册命令号的处理函数，默认记录日志后忽略
+func (s *TcpService) NotFound(h HandleFunc) {
+	s.notFound = h
+}
+
+// Heartbeat 将 cmd 作为心跳包，原样回复
+// 任何消息都会刷新空闲超时，心跳只用于客户端在空闲时保持连接
+func (s *TcpService) Heartbeat(cmd uint32) {
+	s.Handle(cmd, func(c *Context) {
+		_ = c.Reply(cmd, c.Body())
+	})
+}
+
+func (s *TcpService) OnConnect(fn func(conn *Conn)) {
+	s.onConnect = fn
+}
+
+func (s *TcpService) OnDisconnect(fn func(conn *Conn)) {
+	s.onDisconnect = fn
+}
+
+// Count 当前连接数
+func (s *TcpService) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Run tcp server
+// 收到 SIGINT 或 SIGTERM 信号后优雅关闭，处理完进行中的消息再返回
+func (service *TcpService) Run() error {
+	ln, err := net.Listen("tcp", service.opts.Addr)
+	if err != nil {
+		return err
+	}
+	service.listener = ln
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- service.serve(ln)
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return err
+		}
+		// 由 Shutdown 或 Stop 触发，等待关闭流程结束
+		<-service.done
+		return service.shutdownErr
+	case sig := <-sigCh:
+		service.logger.Info("received signal:", sig.String(), ", shutting down")
+		return service.Stop()
+	}
+}
+
+func (service *TcpService) serve(ln net.Listener) error {
+	var delay time.Duration
+
+	for {
+		netConn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-service.closing:
+				return nil
+			default:
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				service.logger.Warn("accept:", err, ", retrying in", delay)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		conn := newConn(service, netConn)
+		if !service.addConn(conn) {
+			_ = netConn.Close()
+			continue
+		}
+
+		go service.serveConn(conn)
+	}
+}
+
+func (service *TcpService) addConn(conn *Conn) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	select {
+	case <-service.closing:
+		return false
+	default:
+	}
+
+	if max := service.opts.MaxConnections; max > 0 && len(service.conns) >= max {
+		service.logger.Warn("too many connections, reject:", conn.RemoteAddr().String())
+		return false
+	}
+
+	service.conns[conn] = struct{}{}
+	service.wg.Add(1)
+	return true
+}
+
+func (service *TcpService) removeConn(conn *Conn) {
+	service.mu.Lock()
+	delete(service.conns, conn)
+	service.mu.Unlock()
+
+	service.wg.Done()
+}
+
+func (service *TcpService) serveConn(conn *Conn) {
+	defer service.removeConn(conn)
+	defer conn.Close()
+	// Codec 和连接回调由使用方实现，panic 时只断开当前连接
+	defer func() {
+		if r := recover(); r != nil {
+			service.logger.Error("panic recovered, conn:", conn.RemoteAddr().String(), ":", r, "\n", string(debug.Stack()))
+		}
+	}()
+
+	if service.onConnect != nil {
+		service.onConnect(conn)
+	}
+	if service.onDisconnect != nil {
+		defer service.onDisconnect(conn)
+	}
+
+	idle := service.opts.GetIdleTimeout()
+	for {
+		if !conn.setReadDeadline(idle) {
+			return
+		}
+
+		p, err := service.codec.Decode(conn.reader)
+		if err != nil {
+			select {
+			case <-service.closing:
+			case <-conn.closed:
+			default:
+				if err != io.EOF {
+					service.logger.Warn("read from", conn.RemoteAddr().String(), ":", err)
+				}
+			}
+			return
+		}
+
+		service.dispatch(conn, p)
+	}
+}
+
+func (service *TcpService) dispatch(conn *Conn, p *Packet) {
+	c := newContext(conn, p)
+
+	defer func() {
+		if r := recover(); r != nil {
+			service.logger.Error("panic recovered, cmd:", p.Cmd, ":", r, "\n", string(debug.Stack()))
+		}
+
+		if service.opts.AccessLog {
+			service.accessLogger.Printf("%s %d %d %s", conn.RemoteAddr().String(), p.Cmd, len(p.Body), time.Since(c.start))
+		}
+	}()
+
+	h, ok := service.handlers[p.Cmd]
+	if !ok {
+		h = service.notFound
+	}
+	if h == nil {
+		service.logger.Warn("no handler for cmd:", p.Cmd)
+		return
+	}
+
+	h(c)
+}
+
+// Shutdown 优雅关闭服务：停止接收新连接，空闲连接立即关闭，等待正在处理的消息完成
+// ctx 超时后强制关闭剩余连接，多次调用只会执行一次
+func (service *TcpService) Shutdown(ctx context.Context) error {
+	service.shutdownOnce.Do(func() {
+		defer close(service.done)
+
+		service.mu.Lock()
+		close(service.closing)
+		conns := make([]*Conn, 0, len(service.conns))
+		for conn := range service.conns {
+			conns = append(conns, conn)
+		}
+		service.mu.Unlock()
+
+		if service.listener != nil {
+			_ = service.listener.Close()
+		}
+
+		for _, conn := range conns {
+			conn.interrupt()
+		}
+
+		wait := make(chan struct{})
+		go func() {
+			service.wg.Wait()
+			close(wait)
+		}()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			service.logger.Error("shutdown:", ctx.Err())
+			service.shutdownErr = ctx.Err()
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}
+
+		if err := service.accessLogger.Flush(); err != nil {
+			service.logger.Error("flush access log:", err)
+		}
+		_ = service.logger.Flush()
+		log.FlushAll()
+	})
+
+	<-service.done
+	return service.shutdownErr
+}
+
+// Stop 按配置的超时时间优雅关闭服务
+func (service *TcpService) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), service.opts.GetShutdownTimeout())
+	defer cancel()
+
+	return service.Shutdown(ctx)
+}
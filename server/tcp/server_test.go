@@ -0,0 +1,224 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// 在回环地址上启动服务，测试结束时关闭
+func startTestService(t *testing.T, s *TcpService) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = ln
+	go s.serve(ln)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	})
+
+	return ln.Addr().String()
+}
+
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	codec  Codec
+}
+
+func dialTest(t *testing.T, addr string, codec Codec) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testClient{conn: conn, reader: bufio.NewReader(conn), codec: codec}
+}
+
+func (c *testClient) send(t *testing.T, cmd uint32, body string) {
+	t.Helper()
+
+	b, err := c.codec.Encode(&Packet{Cmd: cmd, Body: []byte(body)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.conn.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testClient) recv(t *testing.T) *Packet {
+	t.Helper()
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	p, err := c.codec.Decode(c.reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// 等待服务端关闭连接
+func (c *testClient) expectClosed(t *testing.T, timeout time.Duration) {
+	t.Helper()
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	_, err := io.Copy(io.Discard, c.reader)
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		t.Fatalf("connection not closed within %v", timeout)
+	}
+}
+
+func TestDispatchByCmd(t *testing.T) {
+	s := NewTcpService()
+	s.Heartbeat(0)
+	s.Handle(1, func(c *Context) {
+		_ = c.Reply(2, append([]byte("echo "), c.Body()...))
+	})
+	s.Handle(3, func(c *Context) {
+		n, _ := c.Get("count")
+		count, _ := n.(int)
+		c.Set("count", count+1)
+		_ = c.Reply(4, []byte{byte(count + 1)})
+	})
+	s.Handle(9, func(c *Context) {
+		panic("boom")
+	})
+	s.NotFound(func(c *Context) {
+		_ = c.Reply(404, c.Body())
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr, NewLengthPrefixCodec(0))
+
+	tests := []struct {
+		cmd      uint32
+		body     string
+		wantCmd  uint32
+		wantBody string
+	}{
+		{0, "ping", 0, "ping"},
+		{1, "hello", 2, "echo hello"},
+		{3, "", 4, "\x01"},
+		{3, "", 4, "\x02"},
+		{77, "who", 404, "who"},
+	}
+
+	for _, tt := range tests {
+		client.send(t, tt.cmd, tt.body)
+		p := client.recv(t)
+		if p.Cmd != tt.wantCmd || string(p.Body) != tt.wantBody {
+			t.Errorf("cmd %d: got cmd %d body %q, want cmd %d body %q", tt.cmd, p.Cmd, p.Body, tt.wantCmd, tt.wantBody)
+		}
+	}
+
+	// 处理函数 panic 后连接继续可用
+	client.send(t, 9, "")
+	client.send(t, 1, "after panic")
+	if p := client.recv(t); p.Cmd != 2 || string(p.Body) != "echo after panic" {
+		t.Errorf("after panic: got cmd %d body %q", p.Cmd, p.Body)
+	}
+
+	// 连接级别的数据不共享
+	other := dialTest(t, addr, NewLengthPrefixCodec(0))
+	other.send(t, 3, "")
+	if p := other.recv(t); string(p.Body) != "\x01" {
+		t.Errorf("other conn count = %v", p.Body)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	s := NewTcpService()
+	s.opts.IdleTimeoutMs = 150
+	s.Heartbeat(0)
+	disconnected := make(chan struct{}, 1)
+	s.OnDisconnect(func(conn *Conn) {
+		disconnected <- struct{}{}
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr, NewLengthPrefixCodec(0))
+
+	// 心跳刷新空闲超时
+	for i := 0; i < 4; i++ {
+		time.Sleep(75 * time.Millisecond)
+		client.send(t, 0, "")
+		client.recv(t)
+	}
+
+	start := time.Now()
+	client.expectClosed(t, 2*time.Second)
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("closed after %v, before idle timeout", elapsed)
+	}
+
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("OnDisconnect not called")
+	}
+	if n := s.Count(); n != 0 {
+		t.Errorf("Count = %d after idle close", n)
+	}
+}
+
+func TestOversizedFrameClosesConn(t *testing.T) {
+	s := NewTcpService()
+	s.SetCodec(NewLengthPrefixCodec(16))
+	s.Handle(1, func(c *Context) {
+		_ = c.Reply(1, c.Body())
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr, NewLengthPrefixCodec(0))
+	client.send(t, 1, "0123456789abcdefg")
+	client.expectClosed(t, time.Second)
+
+	// 其他连接不受影响
+	other := dialTest(t, addr, NewLengthPrefixCodec(0))
+	other.send(t, 1, "ok")
+	if p := other.recv(t); string(p.Body) != "ok" {
+		t.Errorf("got %q", p.Body)
+	}
+}
+
+func TestCodecPanicClosesConn(t *testing.T) {
+	s := NewTcpService()
+	s.SetCodec(&DelimiterCodec{
+		Delimiter: []byte("\n"),
+		Parse: func(frame []byte) (*Packet, error) {
+			if string(frame) == "panic" {
+				panic("bad frame")
+			}
+			return &Packet{Body: frame}, nil
+		},
+	})
+	s.Handle(0, func(c *Context) {
+		_ = c.Reply(0, c.Body())
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr, NewLineCodec(0))
+	client.send(t, 0, "panic")
+	client.expectClosed(t, time.Second)
+
+	other := dialTest(t, addr, NewLineCodec(0))
+	other.send(t, 0, "still serving")
+	if p := other.recv(t); string(p.Body) != "still serving" {
+		t.Errorf("got %q", p.Body)
+	}
+}
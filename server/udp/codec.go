@@ -0,0 +1,48 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Packet 报文，按 Cmd 分发到对应的处理函数
+type Packet struct {
+	Cmd  uint32
+	Body []byte
+}
+
+// Codec 报文编解码，每个 UDP 报文对应一个 Packet
+type Codec interface {
+	Decode(data []byte) (*Packet, error)
+	Encode(p *Packet) ([]byte, error)
+}
+
+// RawCodec 不解析报文，命令号固定为 0，适合 statsd 之类的文本协议
+type RawCodec struct{}
+
+func (RawCodec) Decode(data []byte) (*Packet, error) {
+	return &Packet{Body: data}, nil
+}
+
+func (RawCodec) Encode(p *Packet) ([]byte, error) {
+	return p.Body, nil
+}
+
+// CmdPrefixCodec 报文以 4 字节大端序命令号开头
+//
+//	| cmd(4) | body |
+type CmdPrefixCodec struct{}
+
+func (CmdPrefixCodec) Decode(data []byte) (*Packet, error) {
+	if len(data) < 4 {
+		return nil, errors.New("udp: packet too short")
+	}
+	return &Packet{Cmd: binary.BigEndian.Uint32(data), Body: data[4:]}, nil
+}
+
+func (CmdPrefixCodec) Encode(p *Packet) ([]byte, error) {
+	buf := make([]byte, 4+len(p.Body))
+	binary.BigEndian.PutUint32(buf, p.Cmd)
+	copy(buf[4:], p.Body)
+	return buf, nil
+}
@@ -0,0 +1,60 @@
+package udp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRawCodec(t *testing.T) {
+	var codec RawCodec
+
+	for _, data := range [][]byte{{}, []byte("metric:1|c")} {
+		p, err := codec.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Cmd != 0 || !bytes.Equal(p.Body, data) {
+			t.Errorf("decode %q = cmd %d body %q", data, p.Cmd, p.Body)
+		}
+
+		b, err := codec.Encode(&Packet{Cmd: 5, Body: data})
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("encode %q = %q, %v", data, b, err)
+		}
+	}
+}
+
+func TestCmdPrefixCodec(t *testing.T) {
+	var codec CmdPrefixCodec
+
+	tests := []struct {
+		cmd  uint32
+		body string
+		wire []byte
+	}{
+		{0, "", []byte{0, 0, 0, 0}},
+		{1, "hi", []byte{0, 0, 0, 1, 'h', 'i'}},
+		{0x01020304, "x", []byte{1, 2, 3, 4, 'x'}},
+	}
+
+	for _, tt := range tests {
+		b, err := codec.Encode(&Packet{Cmd: tt.cmd, Body: []byte(tt.body)})
+		if err != nil || !bytes.Equal(b, tt.wire) {
+			t.Errorf("encode cmd %d = %v, %v, want %v", tt.cmd, b, err, tt.wire)
+		}
+
+		p, err := codec.Decode(tt.wire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Cmd != tt.cmd || string(p.Body) != tt.body {
+			t.Errorf("decode %v = cmd %d body %q", tt.wire, p.Cmd, p.Body)
+		}
+	}
+
+	for _, short := range [][]byte{nil, {1}, {1, 2, 3}} {
+		if _, err := codec.Decode(short); err == nil {
+			t.Errorf("decode %v: expected error", short)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package udp
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+)
+
+type HandleFunc func(c *Context)
+
+// Context 单个报文的处理上下文
+type Context struct {
+	Addr   *net.UDPAddr
+	Packet *Packet
+
+	service *UdpService
+	start   time.Time
+}
+
+func newContext(service *UdpService, addr *net.UDPAddr, p *Packet) *Context {
+	return &Context{
+		Addr:    addr,
+		Packet:  p,
+		service: service,
+		start:   time.Now(),
+	}
+}
+
+func (c *Context) Cmd() uint32 {
+	return c.Packet.Cmd
+}
+
+func (c *Context) Body() []byte {
+	return c.Packet.Body
+}
+
+// BindJSON 将报文内容解析为 JSON
+func (c *Context) BindJSON(v interface{}) error {
+	return json.Unmarshal(c.Packet.Body, v)
+}
+
+// Reply 向来源地址发送命令号为 cmd 的报文
+func (c *Context) Reply(cmd uint32, body []byte) error {
+	return c.service.WriteTo(c.Addr, &Packet{Cmd: cmd, Body: body})
+}
+
+func (c *Context) ReplyJSON(cmd uint32, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Reply(cmd, body)
+}
+
+// ReplyRaw 不经过编码直接发送
+func (c *Context) ReplyRaw(data []byte) error {
+	return c.service.writeRaw(c.Addr, data)
+}
@@ -0,0 +1,68 @@
+package udp
+
+import (
+	"sync"
+	"time"
+)
+
+// 超过该时间没有报文的来源会被清理
+const limiterIdleTTL = time.Minute
+
+// 按来源 IP 限速的令牌桶
+type limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	cleaned time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		cleaned: time.Now(),
+	}
+}
+
+func (l *limiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.cleaned) > limiterIdleTTL {
+		l.cleanup(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *limiter) cleanup(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > limiterIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.cleaned = now
+}
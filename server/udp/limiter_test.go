@@ -0,0 +1,72 @@
+package udp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	type step struct {
+		key   string
+		after time.Duration // 相对起始时间
+		want  bool
+	}
+
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{"burst", 1, 3, []step{
+			{"a", 0, true}, {"a", 0, true}, {"a", 0, true}, {"a", 0, false},
+		}},
+		{"refill", 2, 2, []step{
+			{"a", 0, true}, {"a", 0, true}, {"a", 0, false},
+			// 每秒 2 个，250ms 不足一个令牌
+			{"a", 250 * time.Millisecond, false},
+			{"a", 500 * time.Millisecond, true},
+			{"a", 500 * time.Millisecond, false},
+		}},
+		{"refill capped at burst", 10, 2, []step{
+			{"a", 0, true}, {"a", 0, true},
+			{"a", 10 * time.Second, true}, {"a", 10 * time.Second, true}, {"a", 10 * time.Second, false},
+		}},
+		{"keys are independent", 1, 1, []step{
+			{"a", 0, true}, {"a", 0, false}, {"b", 0, true}, {"b", 0, false},
+			{"a", time.Second, true},
+		}},
+		{"fractional rate", 0.5, 1, []step{
+			{"a", 0, true}, {"a", time.Second, false}, {"a", 2 * time.Second, true},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := newLimiter(tt.rate, tt.burst)
+		start := time.Now()
+		for i, s := range tt.steps {
+			if got := l.allow(s.key, start.Add(s.after)); got != s.want {
+				t.Errorf("%s: step %d (%s at %v) = %v, want %v", tt.name, i, s.key, s.after, got, s.want)
+			}
+		}
+	}
+}
+
+func TestLimiterCleanup(t *testing.T) {
+	l := newLimiter(1, 1)
+	start := time.Now()
+
+	l.allow("a", start)
+	l.allow("b", start.Add(limiterIdleTTL/2))
+	// 超过清理间隔后，空闲超过 TTL 的来源被清理
+	l.allow("c", start.Add(limiterIdleTTL+time.Second))
+
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("idle bucket a not cleaned")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := l.buckets[key]; !ok {
+			t.Errorf("bucket %s should be kept", key)
+		}
+	}
+}
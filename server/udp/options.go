@@ -0,0 +1,82 @@
+package udp
+
+import (
+	"lib/config"
+	"lib/config/proto"
+	"runtime"
+	"time"
+)
+
+const (
+	// UDP 报文的最大长度
+	defaultMaxPacketSize = 65535
+	defaultQueueSize     = 1024
+)
+
+func newOptions(file string) *Options {
+	opts := new(Options)
+	opts.Init(file)
+
+	return opts
+}
+
+type Options struct {
+	proto.UdpConfig
+}
+
+func (opts *Options) Init(file string) {
+	err := config.Resolve(file, &opts.UdpConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if opts.Addr == "" {
+		opts.Addr = ":9091"
+	}
+}
+
+func (opt *Options) GetMaxPacketSize() int {
+	if opt.MaxPacketSize <= 0 || opt.MaxPacketSize > defaultMaxPacketSize {
+		return defaultMaxPacketSize
+	}
+	return opt.MaxPacketSize
+}
+
+// 处理报文的 goroutine 数量，默认为 CPU 核数
+func (opt *Options) GetWorkers() int {
+	if opt.Workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return opt.Workers
+}
+
+// 待处理报文的队列长度，队列满时丢弃新报文
+func (opt *Options) GetQueueSize() int {
+	if opt.QueueSize <= 0 {
+		return defaultQueueSize
+	}
+	return opt.QueueSize
+}
+
+// 每个来源 IP 允许的突发报文数，默认与每秒报文数相同
+func (opt *Options) GetRateBurst() int {
+	if opt.RateBurst <= 0 {
+		burst := int(opt.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+		return burst
+	}
+	return opt.RateBurst
+}
+
+func (opt *Options) GetWriteTimeout() time.Duration {
+	return time.Duration(opt.WriteTimeoutMs) * time.Millisecond
+}
+
+func (opt *Options) GetShutdownTimeout() time.Duration {
+	if opt.ShutdownTimeoutMs <= 0 {
+		return time.Duration(30) * time.Second
+	}
+	return time.Duration(opt.ShutdownTimeoutMs) * time.Millisecond
+}
@@ -0,0 +1,314 @@
+package udp
+
+import (
+	"context"
+	"lib/config"
+	"lib/core"
+	"lib/log"
+	"net"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UdpService UDP 服务
+// 读 goroutine 接收报文放入队列，由固定数量的 worker 并发处理，队列满或来源超过限速时丢弃报文
+//
+//	s := udp.NewUdpService()
+//	s.Handle(0, func(c *udp.Context) {
+//		c.Reply(0, c.Body())
+//	})
+//	s.Run()
+type UdpService struct {
+	core.App
+
+	opts         *Options
+	codec        Codec
+	handlers     map[uint32]HandleFunc
+	notFound     HandleFunc
+	limiter      *limiter
+	conn         *net.UDPConn
+	queue        chan *datagram
+	logger       *log.Logger
+	accessLogger *log.Logger
+	wg           sync.WaitGroup
+
+	// 优雅关闭
+	shutdownOnce sync.Once
+	shutdownErr  error
+	closing      chan struct{}
+	done         chan struct{}
+}
+
+type datagram struct {
+	addr *net.UDPAddr
+	data []byte
+}
+
+func NewUdpService() *UdpService {
+	service := &UdpService{
+		logger:       log.NewLogger(),
+		accessLogger: log.NewLogger(),
+		opts:         newOptions(config.DefaultUdpConfigFile),
+		codec:        RawCodec{},
+		handlers:     make(map[uint32]HandleFunc),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if service.opts.RateLimit > 0 {
+		service.limiter = newLimiter(service.opts.RateLimit, service.opts.GetRateBurst())
+	}
+
+	service.init()
+
+	return service
+}
+
+// 通过 Init 方法初始化
+func (service *UdpService) init() {
+	service.App.Init()
+	service.initLog()
+}
+
+func (service *UdpService) initLog() {
+	if service.opts.AccessLog {
+		logger := log.NewLogger()
+
+		if service.opts.AccessLogDir != "" {
+			logger.SetOutputDir(service.opts.AccessLogDir)
+			logger.SetOutputByName("access.log")
+
+			switch service.opts.AccessLogRotate {
+			case "D", "d", "day", "daily":
+				logger.SetRotateDaily()
+			case "H", "h", "hour", "hourly":
+				logger.SetRotateHourly()
+			default:
+				logger.SetRotateHourly()
+			}
+		}
+
+		service.accessLogger = logger
+	}
+}
+
+func (s *UdpService) Log() *log.Logger {
+	return s.logger
+}
+
+// SetCodec 设置编解码，默认为 RawCodec
+func (s *UdpService) SetCodec(codec Codec) {
+	s.codec = codec
+}
+
+// Handle 注册命令号对应的处理函数，需要在 Run 之前调用
+func (s *UdpService) Handle(cmd uint32, h HandleFunc) {
+	if _, ok := s.handlers[cmd]; ok {
+		panic("udp: duplicate handler for cmd")
+	}
+	s.handlers[cmd] = h
+}
+
+// NotFound 设置未注册命令号的处理函数，默认记录日志后忽略
+func (s *UdpService) NotFound(h HandleFunc) {
+	s.notFound = h
+}
+
+// WriteTo 编码并向 addr 发送报文
+func (s *UdpService) WriteTo(addr *net.UDPAddr, p *Packet) error {
+	data, err := s.codec.Encode(p)
+	if err != nil {
+		return err
+	}
+	return s.writeRaw(addr, data)
+}
+
+func (s *UdpService) writeRaw(addr *net.UDPAddr, data []byte) error {
+	if timeout := s.opts.GetWriteTimeout(); timeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	_, err := s.conn.WriteToUDP(data, addr)
+	return err
+}
+
+// Run udp server
+// 收到 SIGINT 或 SIGTERM 信号后优雅关闭，处理完队列中的报文再返回
+func (service *UdpService) Run() error {
+	addr, err := net.ResolveUDPAddr("udp", service.opts.Addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	service.conn = conn
+
+	if service.opts.ReadBufferSize > 0 {
+		if err := conn.SetReadBuffer(service.opts.ReadBufferSize); err != nil {
+			service.logger.Warn("set read buffer:", err)
+		}
+	}
+	if service.opts.WriteBufferSize > 0 {
+		if err := conn.SetWriteBuffer(service.opts.WriteBufferSize); err != nil {
+			service.logger.Warn("set write buffer:", err)
+		}
+	}
+
+	service.queue = make(chan *datagram, service.opts.GetQueueSize())
+	for i := 0; i < service.opts.GetWorkers(); i++ {
+		service.wg.Add(1)
+		go service.work()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- service.serve()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			_ = service.Stop()
+			return err
+		}
+		// 由 Shutdown 或 Stop 触发，等待关闭流程结束
+		<-service.done
+		return service.shutdownErr
+	case sig := <-sigCh:
+		service.logger.Info("received signal:", sig.String(), ", shutting down")
+		return service.Stop()
+	}
+}
+
+// 超过 MaxPacketSize 的报文会被截断
+func (service *UdpService) serve() error {
+	defer close(service.queue)
+
+	buf := make([]byte, service.opts.GetMaxPacketSize())
+	for {
+		n, addr, err := service.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-service.closing:
+				return nil
+			default:
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				service.logger.Warn("read:", err)
+				continue
+			}
+			return err
+		}
+
+		if service.limiter != nil && !service.limiter.allow(addr.IP.String(), time.Now()) {
+			service.logger.Debug("rate limited, drop packet from", addr.String())
+			continue
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+
+		select {
+		case service.queue <- &datagram{addr: addr, data: data}:
+		default:
+			service.logger.Warn("queue full, drop packet from", addr.String())
+		}
+	}
+}
+
+func (service *UdpService) work() {
+	defer service.wg.Done()
+
+	for d := range service.queue {
+		service.dispatch(d)
+	}
+}
+
+func (service *UdpService) dispatch(d *datagram) {
+	p, err := service.codec.Decode(d.data)
+	if err != nil {
+		service.logger.Warn("decode packet from", d.addr.String(), ":", err)
+		return
+	}
+
+	c := newContext(service, d.addr, p)
+
+	defer func() {
+		if r := recover(); r != nil {
+			service.logger.Error("panic recovered, cmd:", p.Cmd, ":", r, "\n", string(debug.Stack()))
+		}
+
+		if service.opts.AccessLog {
+			service.accessLogger.Printf("%s %d %d %s", d.addr.String(), p.Cmd, len(p.Body), time.Since(c.start))
+		}
+	}()
+
+	h, ok := service.handlers[p.Cmd]
+	if !ok {
+		h = service.notFound
+	}
+	if h == nil {
+		service.logger.Warn("no handler for cmd:", p.Cmd)
+		return
+	}
+
+	h(c)
+}
+
+// Shutdown 优雅关闭服务：停止接收报文，等待队列中的报文处理完成后关闭套接字
+// ctx 超时后直接关闭套接字，多次调用只会执行一次
+func (service *UdpService) Shutdown(ctx context.Context) error {
+	service.shutdownOnce.Do(func() {
+		defer close(service.done)
+
+		close(service.closing)
+		if service.conn == nil {
+			return
+		}
+
+		// 唤醒读 goroutine，处理函数仍然可以回复
+		_ = service.conn.SetReadDeadline(time.Now())
+
+		wait := make(chan struct{})
+		go func() {
+			service.wg.Wait()
+			close(wait)
+		}()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			service.logger.Error("shutdown:", ctx.Err())
+			service.shutdownErr = ctx.Err()
+		}
+		_ = service.conn.Close()
+
+		if err := service.accessLogger.Flush(); err != nil {
+			service.logger.Error("flush access log:", err)
+		}
+		_ = service.logger.Flush()
+		log.FlushAll()
+	})
+
+	<-service.done
+	return service.shutdownErr
+}
+
+// Stop 按配置的超时时间优雅关闭服务
+func (service *UdpService) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), service.opts.GetShutdownTimeout())
+	defer cancel()
+
+	return service.Shutdown(ctx)
+}
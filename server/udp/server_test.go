@@ -0,0 +1,139 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+)
+
+// 与 Run 相同的启动流程，但不等待信号，返回监听地址
+func startTestService(t *testing.T, s *UdpService) *net.UDPAddr {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conn = conn
+	s.queue = make(chan *datagram, s.opts.GetQueueSize())
+	for i := 0; i < s.opts.GetWorkers(); i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	go s.serve()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	})
+
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func dialTest(t *testing.T, addr *net.UDPAddr) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// 读取回复直到超时
+func readReplies(conn *net.UDPConn, timeout time.Duration) []string {
+	var replies []string
+	buf := make([]byte, 1024)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return replies
+		}
+		replies = append(replies, string(buf[:n]))
+	}
+}
+
+func TestDispatchByCmd(t *testing.T) {
+	s := NewUdpService()
+	s.SetCodec(CmdPrefixCodec{})
+	s.Handle(1, func(c *Context) {
+		_ = c.Reply(2, append([]byte("echo "), c.Body()...))
+	})
+	s.Handle(9, func(c *Context) {
+		panic("boom")
+	})
+	s.NotFound(func(c *Context) {
+		_ = c.Reply(404, c.Body())
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr)
+
+	tests := []struct {
+		cmd  uint32
+		body string
+		want string
+	}{
+		{1, "hello", "\x00\x00\x00\x02echo hello"},
+		{77, "who", "\x00\x00\x01\x94who"},
+		// 处理函数 panic 不影响后续报文
+		{9, "", ""},
+		{1, "again", "\x00\x00\x00\x02echo again"},
+	}
+
+	for _, tt := range tests {
+		data, _ := CmdPrefixCodec{}.Encode(&Packet{Cmd: tt.cmd, Body: []byte(tt.body)})
+		if _, err := client.Write(data); err != nil {
+			t.Fatal(err)
+		}
+
+		replies := readReplies(client, 200*time.Millisecond)
+		if tt.want == "" {
+			if len(replies) != 0 {
+				t.Errorf("cmd %d: unexpected replies %q", tt.cmd, replies)
+			}
+			continue
+		}
+		if len(replies) != 1 || replies[0] != tt.want {
+			t.Errorf("cmd %d: replies %q, want %q", tt.cmd, replies, tt.want)
+		}
+	}
+
+	// 无法解码的报文被丢弃
+	if _, err := client.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if replies := readReplies(client, 100*time.Millisecond); len(replies) != 0 {
+		t.Errorf("short packet: unexpected replies %q", replies)
+	}
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	s := NewUdpService()
+	// 突发 2 个，之后每 1000 秒 1 个，测试期间不会补充
+	s.limiter = newLimiter(0.001, 2)
+	s.Handle(0, func(c *Context) {
+		_ = c.ReplyRaw(c.Body())
+	})
+
+	addr := startTestService(t, s)
+	client := dialTest(t, addr)
+
+	for _, msg := range []string{"1", "2", "3", "4", "5"} {
+		if _, err := client.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 多个 worker 并发处理，回复顺序不固定
+	replies := readReplies(client, 300*time.Millisecond)
+	sort.Strings(replies)
+	if len(replies) != 2 || replies[0] != "1" || replies[1] != "2" {
+		t.Errorf("replies = %q, want only the first 2 packets", replies)
+	}
+}